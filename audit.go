@@ -0,0 +1,439 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+)
+
+const (
+	historyAdd = iota
+	historyGet
+	historyAsOf
+	historySnapshot
+)
+
+// Operation is the kind of change recorded in the history of a record.
+type Operation string
+
+// Operations.
+const (
+	Inserted    Operation = "insert"
+	Updated     Operation = "update"
+	Deleted     Operation = "delete"
+	SoftDeleted Operation = "softdelete"
+	Restored    Operation = "restore"
+)
+
+// Change is an entry in the history of a record.
+//
+// Old and New are pointers to records of the audited type holding the values
+// of the record before and after the change, with Old being nil for inserts
+// and New being nil for deletes.
+type Change struct {
+	Operation Operation
+	Time      time.Time
+	Actor     string
+	Old, New  interface{}
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that records the given actor in the history of
+// the audited records that are changed using it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actor(ctx context.Context) interface{} {
+	if a, ok := ctx.Value(actorKey{}).(string); ok {
+		return a
+	}
+
+	return nil
+}
+
+// RegisterAudited registers the given types, as Register, and records every
+// insert, update and delete of their records in a history table, named after
+// the type with a ":history" suffix.
+//
+// Slice fields are not recorded, and nested structs are recorded by key.
+func (s *Store) RegisterAudited(is ...interface{}) error {
+	return s.register(is, true)
+}
+
+func historyTable(name string) string {
+	return name + ":history"
+}
+
+func (s *Store) auditType(i interface{}) error {
+	name := typeName(i)
+
+	t := s.types[name]
+	if t.history != nil {
+		return nil
+	}
+
+	ddl, err := s.historySQL(name, i, &t)
+	if err != nil {
+		return err
+	}
+
+	if err := s.execDDL(ddl); err != nil {
+		return err
+	}
+
+	if t.history, err = s.prepareAll(buildHistorySQL(s.dialect, name, &t)); err != nil {
+		return err
+	}
+
+	s.types[name] = t
+
+	return nil
+}
+
+// historyColumns returns the columns of the history table of a type, after its
+// auto-incrementing id: the operation, time, actor, key columns, and the old
+// and new values of the non-key columns.
+func historyColumns(i interface{}, t *typeInfo) []Column {
+	columns := []Column{{"operation", "TEXT"}, {"time", "INTEGER"}, {"actor", "TEXT"}}
+
+	for n := range t.keys {
+		f := t.keyField(n)
+		columns = append(columns, Column{"key." + f.name, columnType(i, f, true)})
+	}
+
+	for _, image := range [...]string{"old.", "new."} {
+		for pos, f := range t.fields {
+			if !t.isKey(pos) {
+				columns = append(columns, Column{image + f.name, fieldType(i, f)})
+			}
+		}
+	}
+
+	return columns
+}
+
+// historySQL returns the statements that create the history table of a type,
+// or add any columns it is missing.
+func (s *Store) historySQL(name string, i interface{}, t *typeInfo) ([]string, error) {
+	table := historyTable(name)
+	columns := historyColumns(i, t)
+
+	existing, err := s.dialect.Columns(s.db, table)
+	if err != nil {
+		return nil, err
+	} else if len(existing) == 0 {
+		create := "CREATE TABLE IF NOT EXISTS [" + table + "]([id] " + s.dialect.AutoIncrementKey("INTEGER")
+		key := index{name: table + ".key_idx"}
+
+		for _, c := range columns {
+			create += ", [" + c.Name + "] " + s.dialect.ColumnType(c.Type)
+		}
+
+		for n := range t.keys {
+			key.columns = append(key.columns, "key."+t.keyField(n).name)
+		}
+
+		return []string{rebind(s.dialect, create+");"), rebind(s.dialect, key.createSQL(table))}, nil
+	}
+
+	found := make(map[string]struct{}, len(existing))
+
+	for _, c := range existing {
+		found[c.Name] = struct{}{}
+	}
+
+	var ddl []string
+
+	for _, c := range columns {
+		if _, ok := found[c.Name]; !ok {
+			ddl = append(ddl, rebind(s.dialect, "ALTER TABLE ["+table+"] ADD COLUMN ["+c.Name+"] "+s.dialect.ColumnType(c.Type)+";"))
+		}
+	}
+
+	return ddl, nil
+}
+
+func buildHistorySQL(d Dialect, name string, t *typeInfo) []string {
+	var (
+		table               = historyTable(name)
+		columns             = "[operation], [time], [actor]"
+		where, old, current string
+		params              = 3
+	)
+
+	for n := range t.keys {
+		f := t.keyField(n)
+
+		if n > 0 {
+			where += " AND "
+		}
+
+		columns += ", [key." + f.name + "]"
+		where += "[key." + f.name + "] = ?"
+		params++
+	}
+
+	for pos, f := range t.fields {
+		if !t.isKey(pos) {
+			old += ", [old." + f.name + "]"
+			current += ", [new." + f.name + "]"
+			params += 2
+		}
+	}
+
+	queries := make([]string, historySnapshot+1)
+	queries[historyAdd] = "INSERT INTO [" + table + "] (" + columns + old + current + ") VALUES (" + placeholders(params) + ");"
+	queries[historyGet] = "SELECT [operation], [time], [actor]" + old + current + " FROM [" + table + "] WHERE " + where + " ORDER BY [id];"
+	queries[historyAsOf] = "SELECT [operation]" + current + " FROM [" + table + "] WHERE " + where + " AND [time] <= ? ORDER BY [id] DESC LIMIT 1;"
+	queries[historySnapshot] = "SELECT " + t.columns() + " FROM [" + name + "] WHERE " + t.keyWhere() + ";"
+
+	for n, query := range queries {
+		queries[n] = rebind(d, query)
+	}
+
+	return queries
+}
+
+// snapshot returns the stored values of the non-key columns of a record of an
+// audited type, or nil if the record does not exist or the type is not
+// audited.
+func (s *Store) snapshot(ctx context.Context, tx *sql.Tx, t *typeInfo, key []interface{}) ([]interface{}, error) {
+	if t.history == nil {
+		return nil, nil
+	}
+
+	values, err := scanRaw(stmt(tx, t.history[historySnapshot]).QueryRowContext(ctx, key...), len(t.fields))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return values[len(t.keys):], nil
+}
+
+func scanRaw(row *sql.Row, n int, dest ...interface{}) ([]interface{}, error) {
+	values := make([]interface{}, n)
+
+	for m := range values {
+		dest = append(dest, &values[m])
+	}
+
+	return values, row.Scan(dest...)
+}
+
+// audit records a change to a record of an audited type, given the values of
+// the record before the change, as returned by snapshot.
+func (s *Store) audit(ctx context.Context, tx *sql.Tx, t *typeInfo, op Operation, key, old []interface{}) error {
+	if t.history == nil {
+		return nil
+	}
+
+	current, err := s.snapshot(ctx, tx, t, key)
+	if err != nil {
+		return err
+	}
+
+	n := len(t.fields) - len(t.keys)
+	args := make([]interface{}, 0, 3+len(key)+2*n)
+	args = append(args, string(op), time.Now().UnixNano(), actor(ctx))
+	args = append(args, key...)
+
+	for _, values := range [...][]interface{}{old, current} {
+		if values == nil {
+			values = make([]interface{}, n)
+		}
+
+		args = append(args, values...)
+	}
+
+	_, err = stmt(tx, t.history[historyAdd]).ExecContext(ctx, args...)
+
+	return err
+}
+
+// History returns the changes made to the given record, identified by its key,
+// in the order they were made.
+//
+// The nested structs and slice fields of the records in the changes are
+// retrieved with their current values.
+func (s *Store) History(i interface{}) ([]Change, error) {
+	return s.HistoryContext(context.Background(), i)
+}
+
+func (s *Store) HistoryContext(ctx context.Context, i interface{}) ([]Change, error) {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	t, err := s.auditedType(i)
+	if err != nil {
+		return nil, err
+	}
+
+	key := t.GetID(i)
+
+	rows, err := t.history[historyGet].QueryContext(ctx, key...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var (
+		changes []Change
+		is      []interface{}
+		refs    [][]*interface{}
+		n       = len(t.fields) - len(t.keys)
+	)
+
+	for rows.Next() {
+		var (
+			change Change
+			op     string
+			when   int64
+			actor  sql.NullString
+			values = make([]interface{}, 2*n)
+			dest   = []interface{}{&op, &when, &actor}
+		)
+
+		for m := range values {
+			dest = append(dest, &values[m])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		change.Operation = Operation(op)
+		change.Time = time.Unix(0, when).UTC()
+		change.Actor = actor.String
+
+		for _, image := range [...]struct {
+			record *interface{}
+			values []interface{}
+			absent Operation
+		}{
+			{&change.Old, values[:n], Inserted},
+			{&change.New, values[n:], Deleted},
+		} {
+			if change.Operation == image.absent {
+				continue
+			}
+
+			record := reflect.New(t.typ).Interface()
+
+			ref, err := t.scanImage(record, key, image.values)
+			if err != nil {
+				return nil, err
+			}
+
+			*image.record = record
+			is = append(is, record)
+			refs = append(refs, ref)
+		}
+
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.getImageRelated(ctx, &t, is, refs); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// AsOf sets the given record, identified by its key, to its values at the given
+// time, returning ErrNotFound if it did not exist at that time.
+//
+// The nested structs and slice fields of the record are retrieved with their
+// current values.
+func (s *Store) AsOf(i interface{}, when time.Time) error {
+	return s.AsOfContext(context.Background(), i, when)
+}
+
+func (s *Store) AsOfContext(ctx context.Context, i interface{}, when time.Time) error {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	t, err := s.auditedType(i)
+	if err != nil {
+		return err
+	}
+
+	var op string
+
+	key := t.GetID(i)
+
+	values, err := scanRaw(t.history[historyAsOf].QueryRowContext(ctx, append(key, when.UnixNano())...), len(t.fields)-len(t.keys), &op)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && Operation(op) == Deleted {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	ref, err := t.scanImage(i, key, values)
+	if err != nil {
+		return err
+	}
+
+	return s.getImageRelated(ctx, &t, []interface{}{i}, [][]*interface{}{ref})
+}
+
+func (s *Store) auditedType(i interface{}) (typeInfo, error) {
+	if !isPointerStruct(i) {
+		return typeInfo{}, ErrNoPointerStruct
+	}
+
+	t, ok := s.types[typeName(i)]
+	if !ok {
+		return typeInfo{}, ErrUnregisteredType
+	} else if t.history == nil {
+		return typeInfo{}, ErrNotAudited
+	}
+
+	return t, nil
+}
+
+// scanImage sets a record to the given key and recorded values of its non-key
+// columns, returning the references for its struct fields.
+func (t *typeInfo) scanImage(i interface{}, key, values []interface{}) ([]*interface{}, error) {
+	if err := t.SetID(i, key); err != nil {
+		return nil, err
+	}
+
+	vars, refs := t.fieldScanners(i)
+
+	for n, v := range vars {
+		switch v := v.(type) {
+		case *interface{}:
+			*v = values[n]
+		case sql.Scanner:
+			if err := v.Scan(values[n]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, ErrInvalidType
+		}
+	}
+
+	return refs, nil
+}
+
+func (s *Store) getImageRelated(ctx context.Context, t *typeInfo, is []interface{}, refs [][]*interface{}) error {
+	toGet, err := s.getRelated(ctx, nil, t, is, refs)
+	if err != nil {
+		return err
+	} else if len(toGet) > 0 {
+		return s.get(ctx, nil, loadOptions{depth: -1}.related(), toGet...)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type auditedType struct {
+	ID      int
+	Name    string
+	Score   int64
+	Owner   *petOwner
+	Deleted *time.Time `store:"deleted,softdelete"`
+}
+
+type auditedKey struct {
+	Slug  string `key:"1"`
+	Title string
+}
+
+func TestAudit(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.RegisterAudited(new(auditedType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	ctx := WithActor(context.Background(), "alice")
+	owner := petOwner{Name: "Owner"}
+	a := auditedType{Name: "A", Score: 1, Owner: &owner}
+	if err = s.SetContext(ctx, &a); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+	inserted := time.Now()
+	time.Sleep(time.Millisecond)
+	a.Name = "B"
+	a.Score = 2
+	if err = s.Set(&a); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	time.Sleep(time.Millisecond)
+	updated := time.Now()
+	time.Sleep(time.Millisecond)
+	if err = s.RemoveContext(ctx, &a); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	} else if err = s.Restore(&a); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	} else if err = s.Purge(&a); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	}
+	changes, err := s.History(&auditedType{ID: a.ID})
+	if err != nil {
+		t.Fatalf("test 4: received unexpected error: %s", err)
+	}
+	for n, expected := range []struct {
+		operation Operation
+		actor     string
+		old, new  string
+	}{
+		{Inserted, "alice", "", "A"},
+		{Updated, "", "A", "B"},
+		{SoftDeleted, "alice", "B", "B"},
+		{Restored, "", "B", "B"},
+		{Deleted, "", "B", ""},
+	} {
+		if n >= len(changes) {
+			t.Errorf("test %d: missing change", n+5)
+			continue
+		}
+		c := changes[n]
+		if c.Operation != expected.operation || c.Actor != expected.actor {
+			t.Errorf("test %d: expecting operation %s by %q, got %s by %q", n+5, expected.operation, expected.actor, c.Operation, c.Actor)
+		}
+		for _, image := range [...]struct {
+			record interface{}
+			name   string
+		}{
+			{c.Old, expected.old},
+			{c.New, expected.new},
+		} {
+			if image.name == "" {
+				if image.record != nil {
+					t.Errorf("test %d: expecting no record, got %v", n+5, image.record)
+				}
+			} else if r, ok := image.record.(*auditedType); !ok {
+				t.Errorf("test %d: expecting record, got %v", n+5, image.record)
+			} else if r.ID != a.ID || r.Name != image.name || r.Owner == nil || r.Owner.Name != "Owner" {
+				t.Errorf("test %d: expecting record %q with owner, got %v", n+5, image.name, r)
+			}
+		}
+	}
+	if len(changes) != 5 {
+		t.Errorf("test 10: expecting 5 changes, got %d", len(changes))
+	} else if d := changes[2].New.(*auditedType).Deleted; d == nil {
+		t.Errorf("test 10: expecting soft deleted record to have deletion time")
+	}
+	for n, test := range []struct {
+		when time.Time
+		name string
+		err  error
+	}{
+		{inserted.Add(-time.Hour), "", ErrNotFound},
+		{inserted, "A", nil},
+		{updated, "B", nil},
+		{time.Now(), "", ErrNotFound},
+	} {
+		r := auditedType{ID: a.ID}
+		if err = s.AsOf(&r, test.when); err != test.err {
+			t.Errorf("test %d: expecting error %v, got %v", n+11, test.err, err)
+		} else if err == nil && (r.Name != test.name || r.Owner == nil) {
+			t.Errorf("test %d: expecting record %q, got %v", n+11, test.name, r)
+		}
+	}
+	if _, err = s.History(&petOwner{ID: owner.ID}); err != ErrNotAudited {
+		t.Errorf("test 15: expecting error %s, got %v", ErrNotAudited, err)
+	}
+}
+
+func TestAuditKeys(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(auditedKey)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if err = s.Set(&auditedKey{Slug: "a", Title: "Before"}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if err = s.RegisterAudited(new(auditedKey)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Set(&auditedKey{Slug: "a", Title: "After"}, &auditedKey{Slug: "b", Title: "New"}); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if err = s.Insert(&auditedKey{Slug: "c", Title: "Inserted"}); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	for n, test := range []struct {
+		slug     string
+		expected []Change
+	}{
+		{"a", []Change{{Operation: Updated, Old: &auditedKey{"a", "Before"}, New: &auditedKey{"a", "After"}}}},
+		{"b", []Change{{Operation: Inserted, New: &auditedKey{"b", "New"}}}},
+		{"c", []Change{{Operation: Inserted, New: &auditedKey{"c", "Inserted"}}}},
+		{"d", nil},
+	} {
+		changes, err := s.History(&auditedKey{Slug: test.slug})
+		if err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+2, err)
+			continue
+		}
+		for m := range changes {
+			changes[m].Time = time.Time{}
+		}
+		if !reflect.DeepEqual(changes, test.expected) {
+			t.Errorf("test %d: expecting changes %v, got %v", n+2, test.expected, changes)
+		}
+	}
+	if columns, err := SQLite.Columns(s.db, "store.auditedKey:history"); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if len(columns) != 7 {
+		t.Errorf("test 6: expecting 7 history columns, got %v", columns)
+	}
+}
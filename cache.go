@@ -0,0 +1,233 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// CacheStats contains the statistics of the cache of a type.
+type CacheStats struct {
+	Hits, Misses, Evictions uint64
+	Size                    int
+}
+
+type cacheEntry struct {
+	key     interface{}
+	values  []interface{}
+	expires time.Time
+}
+
+// cache is an LRU cache of the stored values of the non-key columns of
+// records, keyed by the mapKey of their keys.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[interface{}]*list.Element
+	lru     list.List
+	gen     uint64
+	stats   CacheStats
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[interface{}]*list.Element),
+	}
+}
+
+// get returns a copy of the cached values for a key.
+func (c *cache) get(key interface{}) ([]interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if ok && c.ttl > 0 && time.Now().After(e.Value.(*cacheEntry).expires) {
+		c.remove(e)
+
+		ok = false
+	}
+
+	if !ok {
+		c.stats.Misses++
+
+		return nil, false
+	}
+
+	c.stats.Hits++
+
+	c.lru.MoveToFront(e)
+
+	values := append([]interface{}{}, e.Value.(*cacheEntry).values...)
+
+	for n, v := range values {
+		if b, ok := v.([]byte); ok {
+			values[n] = append([]byte{}, b...)
+		}
+	}
+
+	return values, true
+}
+
+// generation returns the current generation of the cache, which is to be
+// retrieved before querying for records to be added with put.
+func (c *cache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// put adds values to the cache, unless the cache has been invalidated since
+// the given generation, as the values may then be out of date.
+func (c *cache) put(key interface{}, values []interface{}, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		values:  values,
+		expires: time.Now().Add(c.ttl),
+	})
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+
+		c.stats.Evictions++
+	}
+}
+
+func rawValues(scanned []interface{}) []interface{} {
+	values := make([]interface{}, len(scanned))
+
+	for n, v := range scanned {
+		values[n] = *v.(*interface{})
+	}
+
+	return values
+}
+
+func (c *cache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cacheEntry).key)
+}
+
+// invalidate removes the given key from the cache, or every key when nil.
+func (c *cache) invalidate(key interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+
+	if key == nil {
+		c.entries = make(map[interface{}]*list.Element)
+		c.lru.Init()
+	} else if e, ok := c.entries[key]; ok {
+		c.remove(e)
+	}
+}
+
+func (c *cache) statistics() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.lru.Len()
+
+	return stats
+}
+
+// SetCache sets the size and TTL of an LRU cache for retrieving records of the
+// type of the given record by key, replacing any existing cache. A size of
+// zero, or less, disables the cache, and a TTL of zero keeps records until
+// they are evicted.
+//
+// Records are only cached when retrieved outside of a transaction, and are
+// removed from the cache when a change to them, through the Store, is
+// committed.
+func (s *Store) SetCache(i interface{}, size int, ttl time.Duration) error {
+	if !isPointerStruct(i) {
+		return ErrNoPointerStruct
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.typesMutex.Lock()
+	defer s.typesMutex.Unlock()
+
+	name := typeName(i)
+
+	t, ok := s.types[name]
+	if !ok {
+		return ErrUnregisteredType
+	}
+
+	t.cache = nil
+
+	if size > 0 {
+		t.cache = newCache(size, ttl)
+	}
+
+	s.types[name] = t
+
+	return nil
+}
+
+// CacheStats returns the statistics of the cache of the type of the given
+// record.
+func (s *Store) CacheStats(i interface{}) (CacheStats, error) {
+	if !isPointerStruct(i) {
+		return CacheStats{}, ErrNoPointerStruct
+	}
+
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	t, ok := s.types[typeName(i)]
+	if !ok {
+		return CacheStats{}, ErrUnregisteredType
+	} else if t.cache == nil {
+		return CacheStats{}, nil
+	}
+
+	return t.cache.statistics(), nil
+}
+
+// invalidateCaches removes a changed record from the cache of its type, and,
+// when it was deleted, clears the caches of the types that reference it, as
+// their references may have been cleared.
+func (s *Store) invalidateCaches(e Event) {
+	t := s.types[e.Type]
+	if t.cache != nil {
+		t.cache.invalidate(mapKey(e.Key))
+	}
+
+	if e.Operation != Deleted {
+		return
+	}
+
+	for _, rt := range s.types {
+		if rt.cache == nil {
+			continue
+		}
+
+		for _, f := range rt.fields {
+			if f.ref == e.Type {
+				rt.cache.invalidate(nil)
+
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+type cachedType struct {
+	ID    int
+	Name  string
+	Data  []byte
+	Owner *petOwner `delete:"set-null"`
+}
+
+func TestCache(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(cachedType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if err = s.SetCache(new(cachedType), 2, 0); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	owner := petOwner{Name: "Owner"}
+	if err = s.Set(&cachedType{Name: "A", Data: []byte("a"), Owner: &owner}, &cachedType{Name: "B"}, &cachedType{Name: "C"}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	get := func(id int) cachedType {
+		c := cachedType{ID: id}
+		if err := s.Get(&c); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		return c
+	}
+	for n, test := range []struct {
+		fn       func() string
+		expected string
+		stats    CacheStats
+	}{
+		{func() string { return get(1).Name }, "A", CacheStats{Misses: 1, Size: 1}},
+		{
+			func() string {
+				c := get(1)
+				c.Data[0] = 'z'
+				return c.Name + string(c.Data) + c.Owner.Name
+			},
+			"AzOwner",
+			CacheStats{Hits: 1, Misses: 1, Size: 1},
+		},
+		{func() string { return string(get(1).Data) }, "a", CacheStats{Hits: 2, Misses: 1, Size: 1}},
+		{func() string { return get(2).Name + get(3).Name }, "BC", CacheStats{Hits: 2, Misses: 3, Evictions: 1, Size: 2}},
+		{func() string { return get(1).Name }, "A", CacheStats{Hits: 2, Misses: 4, Evictions: 2, Size: 2}},
+		{
+			func() string {
+				s.db.Exec("UPDATE [store.cachedType] SET [Name] = 'Changed' WHERE [ID] = 1;")
+				return get(1).Name
+			},
+			"A",
+			CacheStats{Hits: 3, Misses: 4, Evictions: 2, Size: 2},
+		},
+		{
+			func() string {
+				if err := s.Set(&cachedType{ID: 1, Name: "D", Owner: &owner}); err != nil {
+					return err.Error()
+				}
+				return get(1).Name
+			},
+			"D",
+			CacheStats{Hits: 3, Misses: 5, Evictions: 2, Size: 2},
+		},
+		{
+			func() string {
+				tx, err := s.Begin()
+				if err != nil {
+					return err.Error()
+				}
+				c := cachedType{ID: 1}
+				tx.Set(&cachedType{ID: 1, Name: "E"})
+				tx.Get(&c)
+				tx.Rollback()
+				return c.Name + get(1).Name
+			},
+			"ED",
+			CacheStats{Hits: 4, Misses: 5, Evictions: 2, Size: 2},
+		},
+		{func() string { return get(1).Name }, "D", CacheStats{Hits: 5, Misses: 5, Evictions: 2, Size: 2}},
+		{
+			func() string {
+				if err := s.Remove(&owner); err != nil {
+					return err.Error()
+				} else if c := get(1); c.Owner != nil {
+					return c.Owner.Name
+				}
+				return "removed"
+			},
+			"removed",
+			CacheStats{Hits: 5, Misses: 6, Evictions: 2, Size: 1},
+		},
+		{
+			func() string {
+				s.Remove(&cachedType{ID: 1})
+				return get(1).Name
+			},
+			"",
+			CacheStats{Hits: 5, Misses: 7, Evictions: 2},
+		},
+	} {
+		if got := test.fn(); got != test.expected {
+			t.Errorf("test %d: expecting %q, got %q", n+1, test.expected, got)
+		} else if stats, err := s.CacheStats(new(cachedType)); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if stats != test.stats {
+			t.Errorf("test %d: expecting stats %+v, got %+v", n+1, test.stats, stats)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if err = s.SetCache(new(testType), 10, 10*time.Millisecond); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if err = s.Set(&testType{Data: "A"}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tt := testType{ID: 1}
+	if err = s.Get(&tt); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	s.db.Exec("UPDATE [store.testType] SET [Data] = 'B' WHERE [ID] = 1;")
+	if err = s.Get(&tt); err != nil || tt.Data != "A" {
+		t.Errorf("test 2: expecting cached data %q, got %q, %v", "A", tt.Data, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err = s.Get(&tt); err != nil || tt.Data != "B" {
+		t.Errorf("test 3: expecting expired data to be reloaded as %q, got %q, %v", "B", tt.Data, err)
+	}
+	if stats, err := s.CacheStats(new(testType)); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if expected := (CacheStats{Hits: 1, Misses: 2, Size: 1}); stats != expected {
+		t.Errorf("test 4: expecting stats %+v, got %+v", expected, stats)
+	}
+	if err = s.SetCache(new(testType), 0, 0); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if stats, err := s.CacheStats(new(testType)); err != nil || stats != (CacheStats{}) {
+		t.Errorf("test 5: expecting empty stats, got %+v, %v", stats, err)
+	}
+}
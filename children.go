@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+const (
+	childAdd = iota
+	childGet
+	childRemove
+)
+
+type child struct {
+	pos        int
+	name       string
+	table      string
+	parentType string
+	sqlType    string
+	isStruct   bool
+	isPointer  bool
+	encoding   uint8
+	onDelete   uint8
+	ref        string
+	lazy       bool
+	statements []*sql.Stmt
+}
+
+func sliceChild(parent, parentType, fieldName string, pos int, f reflect.StructField, nested func(interface{}) error) (child, error) {
+	c := child{
+		pos:        pos,
+		name:       fieldName,
+		table:      parent + "." + fieldName,
+		parentType: parentType,
+	}
+
+	et := f.Type.Elem()
+	if et.Kind() == reflect.Ptr {
+		c.isPointer = true
+		et = et.Elem()
+	}
+
+	if typ := sqlType(et); typ != "" {
+		if c.isPointer {
+			return child{}, ErrInvalidType
+		}
+
+		c.sqlType = typ
+		c.encoding = typeEncoding(et)
+
+		if typ := f.Tag.Get("type"); typ != "" {
+			c.sqlType = typ
+		}
+	} else if et.Kind() == reflect.Struct {
+		if err := nested(reflect.New(et).Interface()); err != nil {
+			return child{}, err
+		}
+
+		_, refType, err := referencedKey(et)
+		if err != nil {
+			return child{}, err
+		}
+
+		c.isStruct = true
+		c.sqlType = refType
+		c.ref = typeName(reflect.New(et).Interface())
+	} else {
+		return child{}, ErrInvalidType
+	}
+
+	return c, nil
+}
+
+func buildChildSQL(d Dialect, table, parentType, sqlType string) (string, []string) {
+	queries := make([]string, 3)
+	queries[childAdd] = rebind(d, "INSERT INTO ["+table+"] ([parent], [pos], [value]) VALUES (?, ?, ?);")
+	queries[childGet] = rebind(d, "SELECT [value] FROM ["+table+"] WHERE [parent] = ? ORDER BY [pos];")
+	queries[childRemove] = rebind(d, "DELETE FROM ["+table+"] WHERE [parent] = ?;")
+
+	return rebind(d, "CREATE TABLE IF NOT EXISTS ["+table+"]([parent] "+d.ColumnType(indexedType(parentType))+", [pos] "+d.ColumnType("INTEGER")+", [value] "+d.ColumnType(sqlType)+", PRIMARY KEY ([parent], [pos]));"), queries
+}
+
+func (s *Store) setChildren(ctx context.Context, tx *sql.Tx, i interface{}, t *typeInfo, id interface{}, toSet *[]interface{}) error {
+	for _, c := range t.children {
+		if _, err := stmt(tx, c.statements[childRemove]).ExecContext(ctx, id); err != nil {
+			return err
+		}
+
+		v := reflect.ValueOf(i).Elem().Field(c.pos)
+
+		for n := 0; n < v.Len(); n++ {
+			var (
+				e     = v.Index(n)
+				value interface{}
+				err   error
+			)
+
+			if c.isStruct {
+				if c.isPointer {
+					if e.IsNil() {
+						continue
+					}
+				} else {
+					e = e.Addr()
+				}
+
+				ni := e.Interface()
+				nt := s.types[typeName(ni)]
+
+				if err = s.set(ctx, tx, ni, &nt, toSet, setUpsert); err != nil {
+					return err
+				}
+
+				value = nt.GetID(ni)[0]
+			} else if value, err = encodeValue(e.Addr().Interface(), c.encoding); err != nil {
+				return err
+			}
+
+			if _, err = stmt(tx, c.statements[childAdd]).ExecContext(ctx, id, n, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) getChildren(ctx context.Context, tx *sql.Tx, t *typeInfo, is []interface{}) ([]interface{}, error) {
+	if len(t.children) == 0 {
+		return nil, nil
+	}
+
+	var ids []interface{}
+
+	seen := make(map[interface{}]struct{}, len(is))
+
+	for _, i := range is {
+		id := t.GetID(i)
+		if _, ok := seen[mapKey(id)]; !ok {
+			seen[mapKey(id)] = struct{}{}
+			ids = append(ids, id[0])
+		}
+	}
+
+	var toGet []interface{}
+
+	for _, c := range t.children {
+		values, err := s.childValues(ctx, tx, t, &c, t.typ.Field(c.pos).Type.Elem(), ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range is {
+			v := reflect.ValueOf(i).Elem().Field(c.pos)
+			vs := values[mapKey(t.GetID(i))]
+
+			if len(vs) == 0 {
+				v.Set(reflect.Zero(v.Type()))
+
+				continue
+			}
+
+			slice := reflect.MakeSlice(v.Type(), len(vs), len(vs))
+
+			for n, value := range vs {
+				e := slice.Index(n)
+
+				if !c.isStruct {
+					e.Set(value)
+
+					continue
+				}
+
+				if c.isPointer {
+					e.Set(reflect.New(e.Type().Elem()))
+				} else {
+					e = e.Addr()
+				}
+
+				ni := e.Interface()
+				nt := s.types[c.ref]
+
+				if err := nt.SetID(ni, []interface{}{value.Interface()}); err != nil {
+					return nil, err
+				}
+
+				if !c.lazy {
+					toGet = append(toGet, ni)
+				}
+			}
+
+			v.Set(slice)
+		}
+	}
+
+	return toGet, nil
+}
+
+func (s *Store) childValues(ctx context.Context, tx *sql.Tx, t *typeInfo, c *child, et reflect.Type, ids []interface{}) (map[interface{}][]reflect.Value, error) {
+	values := make(map[interface{}][]reflect.Value)
+	parent := reflect.New(t.typ).Interface()
+	parentKey := t.keyScanners(parent)[0]
+
+	if c.isStruct {
+		et = reflect.TypeOf((*interface{})(nil)).Elem()
+	}
+
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > maxBatch {
+			batch = batch[:maxBatch]
+		}
+
+		ids = ids[len(batch):]
+
+		rows, err := s.query(ctx, tx, "SELECT [parent], [value] FROM ["+c.table+"] WHERE [parent] IN ("+placeholders(len(batch))+") ORDER BY [parent], [pos];", batch...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			e := reflect.New(et)
+
+			if err = rows.Scan(parentKey, scanner(e.Interface(), c.encoding)); err != nil {
+				break
+			}
+
+			key := mapKey(t.GetID(parent))
+			values[key] = append(values[key], e.Elem())
+		}
+
+		rows.Close()
+
+		if err != nil {
+			return nil, err
+		} else if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (s *Store) removeChildren(ctx context.Context, tx *sql.Tx, t *typeInfo, id interface{}) error {
+	for _, c := range t.children {
+		if _, err := stmt(tx, c.statements[childRemove]).ExecContext(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
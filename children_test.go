@@ -0,0 +1,153 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+type lineItem struct {
+	ID       int
+	Name     string
+	Quantity int
+}
+
+type order struct {
+	ID       int
+	Customer string
+	Tags     []string
+	Items    []lineItem
+	Extras   []*lineItem
+}
+
+type tagged struct {
+	ID   int
+	Tags []string
+}
+
+type mapOrder struct {
+	ID     int
+	Totals map[string]int
+}
+
+type skippedMapOrder struct {
+	ID       int
+	Customer string
+	Totals   map[string]int `store:"-"`
+}
+
+func TestChildren(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(order)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	o := order{
+		Customer: "Alice",
+		Tags:     []string{"urgent", "gift"},
+		Items: []lineItem{
+			{Name: "Beep", Quantity: 2},
+			{Name: "Boop", Quantity: 1},
+		},
+		Extras: []*lineItem{
+			{Name: "Wrapping", Quantity: 1},
+		},
+	}
+	if err = s.Set(&o); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	got := order{ID: o.ID}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(got, o) {
+		t.Errorf("test 2: expecting %v, got %v", o, got)
+	}
+	o.Tags = []string{"gift"}
+	o.Items = o.Items[1:]
+	o.Items[0].Quantity = 5
+	o.Extras = nil
+	if err = s.Set(&o); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	}
+	got = order{ID: o.ID}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 4: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(got, o) {
+		t.Errorf("test 4: expecting %v, got %v", o, got)
+	}
+	if err = s.Remove(&o); err != nil {
+		t.Fatalf("test 5: received unexpected error: %s", err)
+	}
+	var n int
+	if err = s.db.QueryRow("SELECT COUNT(1) FROM [store.order.Tags];").Scan(&n); err != nil {
+		t.Fatalf("test 6: received unexpected error: %s", err)
+	} else if n != 0 {
+		t.Errorf("test 6: expecting no child rows, got %d", n)
+	}
+}
+
+func TestChildrenOnly(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(tagged)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	a, b := tagged{Tags: []string{"a", "b"}}, tagged{}
+	if err = s.Set(&a, &b); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if a.ID != 1 || b.ID != 2 {
+		t.Errorf("test 1: expecting IDs 1 and 2, got %d and %d", a.ID, b.ID)
+	}
+	got := tagged{ID: 1}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(got, a) {
+		t.Errorf("test 2: expecting %v, got %v", a, got)
+	}
+	for n, d := range []Dialect{SQLite, PostgreSQL, MySQL} {
+		expected := []string{
+			"INSERT INTO [store.tagged] DEFAULT VALUES;",
+			"INSERT INTO \"store.tagged\" DEFAULT VALUES RETURNING \"ID\";",
+			"INSERT INTO `store.tagged` () VALUES ();",
+		}[n]
+		if _, queries := buildSQL(d, "store.tagged", new(tagged), []field{{pos: 0, name: "ID"}}, []int{0}, true); queries[add] != expected {
+			t.Errorf("test %d: expecting SQL %q, got %q", n+3, expected, queries[add])
+		}
+	}
+}
+
+func TestMapFields(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(mapOrder)); err != ErrMapField {
+		t.Errorf("test 1: expecting error %s, got %v", ErrMapField, err)
+	} else if err = s.Register(new(skippedMapOrder)); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	}
+	for n, i := range []interface{}{
+		&struct {
+			ID    int
+			Names []*string
+		}{},
+		&struct {
+			ID   int
+			Grid [][]int
+		}{},
+		&struct {
+			ID    int
+			Funcs []func()
+		}{},
+	} {
+		if err = s.Register(i); err != ErrInvalidType {
+			t.Errorf("test %d: expecting error %s, got %v", n+3, ErrInvalidType, err)
+		}
+	}
+}
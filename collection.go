@@ -0,0 +1,121 @@
+package store
+
+import "context"
+
+// Collection provides a type-safe wrapper around a Store for a single
+// registered type.
+type Collection[T any] struct {
+	store *Store
+}
+
+// NewCollection registers the type T with the given Store and returns a
+// Collection for it.
+func NewCollection[T any](s *Store) (*Collection[T], error) {
+	if err := s.Register(new(T)); err != nil {
+		return nil, err
+	}
+
+	return &Collection[T]{store: s}, nil
+}
+
+// Get retrieves the record with the given key, which should have a value for
+// each key column.
+func (c *Collection[T]) Get(key ...interface{}) (*T, error) {
+	v := new(T)
+
+	c.store.typesMutex.RLock()
+	defer c.store.typesMutex.RUnlock()
+
+	t := c.store.types[typeName(v)]
+
+	if err := t.SetID(v, key); err != nil {
+		return nil, err
+	} else if err := c.store.get(context.Background(), nil, loadOptions{depth: -1}, v); err != nil {
+		return nil, err
+	} else if !t.hasID(v) {
+		return nil, ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (c *Collection[T]) Set(vs ...*T) error {
+	return c.store.Set(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Insert(vs ...*T) error {
+	return c.store.Insert(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Update(vs ...*T) error {
+	return c.store.Update(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Upsert(vs ...*T) error {
+	return c.store.Upsert(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Remove(vs ...*T) error {
+	return c.store.Remove(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Purge(vs ...*T) error {
+	return c.store.Purge(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Restore(vs ...*T) error {
+	return c.store.Restore(toInterfaces(vs)...)
+}
+
+func (c *Collection[T]) Count() (int, error) {
+	return c.store.Count(new(T))
+}
+
+func (c *Collection[T]) Page(offset, limit int) ([]T, error) {
+	return getSlice[T](limit, func(is []interface{}) (int, error) {
+		return c.store.GetPage(is, offset)
+	})
+}
+
+func (c *Collection[T]) Search(filter Filter, offset, limit int, sort ...SortBy) ([]T, error) {
+	s := c.store.NewSearch(new(T))
+	s.Filter = filter
+	s.Sort = sort
+
+	p, err := s.Prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	defer p.Close()
+
+	return getSlice[T](limit, func(is []interface{}) (int, error) {
+		return p.GetPage(is, offset)
+	})
+}
+
+func getSlice[T any](limit int, fn func([]interface{}) (int, error)) ([]T, error) {
+	vs := make([]T, limit)
+	is := make([]interface{}, limit)
+
+	for n := range vs {
+		is[n] = &vs[n]
+	}
+
+	n, err := fn(is)
+	if err != nil {
+		return nil, err
+	}
+
+	return vs[:n], nil
+}
+
+func toInterfaces[T any](vs []*T) []interface{} {
+	is := make([]interface{}, len(vs))
+
+	for n, v := range vs {
+		is[n] = v
+	}
+
+	return is
+}
@@ -0,0 +1,51 @@
+//go:build go1.23
+
+package store
+
+import "iter"
+
+// All returns an iterator over the records matching the filter, in the given
+// order, streamed using a Cursor.
+func (c *Collection[T]) All(filter Filter, sort ...SortBy) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		s := c.store.NewSearch(new(T))
+		s.Filter = filter
+		s.Sort = sort
+
+		p, err := s.Prepare()
+		if err != nil {
+			yield(nil, err)
+
+			return
+		}
+
+		defer p.Close()
+
+		cur, err := p.Cursor()
+		if err != nil {
+			yield(nil, err)
+
+			return
+		}
+
+		defer cur.Close()
+
+		for cur.Next() {
+			v := new(T)
+
+			if err := cur.Scan(v); err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if err := cur.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
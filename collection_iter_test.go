@@ -0,0 +1,59 @@
+//go:build go1.23
+
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollectionAll(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	c, err := NewCollection[testType](s)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	values := []*testType{
+		{0, "One", 1},
+		{0, "Two", 2},
+		{0, "Three", 3},
+		{0, "Four", 4},
+	}
+	if err = c.Set(values...); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	var got []*testType
+	for tt, err := range c.All(Gt("Number", 1), SortBy{"Number", false}) {
+		if err != nil {
+			t.Fatalf("test 1: received unexpected error: %s", err)
+		}
+		got = append(got, tt)
+	}
+	if expected := []*testType{values[3], values[2], values[1]}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("test 1: expecting %v, got %v", expected, got)
+	}
+	got = got[:0]
+	for tt, err := range c.All(nil) {
+		if err != nil {
+			t.Fatalf("test 2: received unexpected error: %s", err)
+		}
+		got = append(got, tt)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if expected := []*testType{values[0], values[1]}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("test 2: expecting %v, got %v", expected, got)
+	}
+	var errs []error
+	for _, err := range c.All(Gt("Missing", 1)) {
+		errs = append(errs, err)
+	}
+	if expected := []error{ErrUnknownColumn}; !reflect.DeepEqual(errs, expected) {
+		t.Errorf("test 3: expecting errors %v, got %v", expected, errs)
+	}
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollection(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	c, err := NewCollection[testType](s)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	values := []*testType{
+		{0, "One", 1},
+		{0, "Two", 2},
+		{0, "Three", 3},
+	}
+	if err = c.Set(values...); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if tt, err := c.Get(2); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if *tt != *values[1] {
+		t.Errorf("test 2: expecting %v, got %v", values[1], tt)
+	}
+	if _, err = c.Get(4); err != ErrNotFound {
+		t.Errorf("test 3: expecting error %s, got %v", ErrNotFound, err)
+	}
+	if tts, err := c.Page(1, 5); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if expected := []testType{*values[1], *values[2]}; !reflect.DeepEqual(tts, expected) {
+		t.Errorf("test 4: expecting %v, got %v", expected, tts)
+	}
+	if tts, err := c.Search(Gt("Number", 1), 0, 5, SortBy{"Number", false}); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if expected := []testType{*values[2], *values[1]}; !reflect.DeepEqual(tts, expected) {
+		t.Errorf("test 5: expecting %v, got %v", expected, tts)
+	}
+	if err = c.Remove(values[0]); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if n, err := c.Count(); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if n != 2 {
+		t.Errorf("test 6: expecting count 2, got %d", n)
+	}
+}
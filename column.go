@@ -0,0 +1,174 @@
+package store
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// constraints returns the column constraints declared by the options of a
+// field.
+func (f field) constraints() string {
+	var c string
+
+	if f.def != "" {
+		c += " DEFAULT " + f.def
+	}
+
+	if f.notNull {
+		c += " NOT NULL"
+	}
+
+	if f.check != "" {
+		c += " CHECK (" + f.check + ")"
+	}
+
+	return c
+}
+
+// hasColumnOptions returns true if any of the options only apply to columns.
+func hasColumnOptions(opts []string) bool {
+	for _, opt := range [...]string{"unique", "notnull", "created", "updated", "version", "softdelete"} {
+		if hasOption(opts, opt) {
+			return true
+		}
+	}
+
+	return optionValue(opts, "default") != "" || optionValue(opts, "check") != ""
+}
+
+// validOptions returns true if all of the options are known, with values given
+// to, and only to, those that take them.
+func validOptions(opts []string) bool {
+	for _, opt := range opts {
+		switch name, value, hasValue := strings.Cut(opt, "="); name {
+		case "unique", "notnull", "created", "updated", "version", "softdelete", "lazy":
+			if hasValue {
+				return false
+			}
+		case "default", "check", "time":
+			if value == "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// optionValue returns the value of an option of the form name=value.
+func optionValue(opts []string, name string) string {
+	for _, o := range opts {
+		if strings.HasPrefix(o, name+"=") {
+			return o[len(name)+1:]
+		}
+	}
+
+	return ""
+}
+
+// ptrScanner scans into a pointer field, setting it to nil for NULL values and
+// allocating a new value otherwise.
+type ptrScanner struct {
+	value    reflect.Value
+	encoding uint8
+}
+
+func (p ptrScanner) Scan(src interface{}) error {
+	if src == nil {
+		p.value.Set(reflect.Zero(p.value.Type()))
+
+		return nil
+	}
+
+	v := reflect.New(p.value.Type().Elem())
+
+	if err := scanner(v.Interface(), p.encoding).(sql.Scanner).Scan(src); err != nil {
+		return err
+	}
+
+	p.value.Set(v)
+
+	return nil
+}
+
+// nullScanner scans into a plain field, using the conversions of the sql.Null
+// types and setting the zero value for NULL values.
+type nullScanner struct {
+	reflect.Value
+}
+
+func (n nullScanner) Scan(src interface{}) error {
+	if src == nil {
+		n.Set(reflect.Zero(n.Type()))
+
+		return nil
+	}
+
+	switch n.Kind() {
+	case reflect.Bool:
+		var b sql.NullBool
+
+		if err := b.Scan(src); err != nil {
+			return err
+		}
+
+		n.SetBool(b.Bool)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i sql.NullInt64
+
+		if err := i.Scan(src); err != nil {
+			return err
+		}
+
+		n.SetInt(i.Int64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var i sql.NullInt64
+
+		if err := i.Scan(src); err != nil {
+			return err
+		}
+
+		n.SetUint(uint64(i.Int64))
+	case reflect.Float32, reflect.Float64:
+		var f sql.NullFloat64
+
+		if err := f.Scan(src); err != nil {
+			return err
+		}
+
+		n.SetFloat(f.Float64)
+	case reflect.String:
+		var s sql.NullString
+
+		if err := s.Scan(src); err != nil {
+			return err
+		}
+
+		n.SetString(s.String)
+	case reflect.Slice:
+		var s sql.NullString
+
+		if err := s.Scan(src); err != nil {
+			return err
+		}
+
+		n.SetBytes([]byte(s.String))
+	default:
+		if n.Type() != timeType {
+			return ErrInvalidType
+		}
+
+		var t sql.NullTime
+
+		if err := t.Scan(src); err != nil {
+			return err
+		}
+
+		n.Set(reflect.ValueOf(t.Time))
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nullableType struct {
+	ID     int
+	Name   *string
+	Number *int64
+	Flag   *bool
+	Text   *uuid
+	Count  sql.NullInt64
+	Label  sql.NullString
+	When   sql.NullTime
+}
+
+type constrainedType struct {
+	ID    int
+	Name  string    `store:"name,notnull,default='unknown'"`
+	Age   int       `store:"age,check=[age] >= 0"`
+	Owner *petOwner `delete:"set-null"`
+}
+
+type listedType struct {
+	ID     int
+	Status string `store:"status,default='a,b',check=[status] IN ('a,b', 'c')"`
+}
+
+type badMisspeltOption struct {
+	ID   int
+	Name string `store:"name,notnul"`
+}
+
+type badEmptyDefault struct {
+	ID   int
+	Name string `store:"name,default="`
+}
+
+type badNotNull struct {
+	ID    int
+	Owner *petOwner `store:"owner,notnull" delete:"set-null"`
+}
+
+type badNotNullCascade struct {
+	ID    int
+	Owner *petOwner `store:"owner,notnull" delete:"cascade"`
+}
+
+type badSliceOption struct {
+	ID    int
+	Names []string `store:"names,notnull"`
+}
+
+func TestNullable(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(nullableType), new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if ddl, err := s.PlanMigration(new(nullableType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if len(ddl) != 0 {
+		t.Fatalf("expecting no DDL, got %q", ddl)
+	}
+	name, number, flag := "Name", int64(7), true
+	values := []nullableType{
+		{},
+		{0, &name, &number, &flag, &uuid{1, 2}, sql.NullInt64{Int64: 3, Valid: true}, sql.NullString{String: "label", Valid: true}, sql.NullTime{Time: time.Unix(1e9, 0).UTC(), Valid: true}},
+	}
+	for n := range values {
+		if err = s.Set(&values[n]); err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		got := nullableType{ID: values[n].ID, Name: new(string), Count: sql.NullInt64{Int64: 1, Valid: true}, When: sql.NullTime{Valid: true}}
+		if err = s.Get(&got); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if !reflect.DeepEqual(got, values[n]) {
+			t.Errorf("test %d: expecting %v, got %v", n+1, values[n], got)
+		}
+	}
+	var typ string
+	if err = s.db.QueryRow("SELECT typeof([Count]) FROM [store.nullableType] WHERE [ID] = 2;").Scan(&typ); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if typ != "integer" {
+		t.Errorf("test 3: expecting integer, got %s", typ)
+	}
+	if _, err = s.db.Exec("INSERT INTO [store.testType] ([ID], [Data], [Number]) VALUES (1, NULL, NULL);"); err != nil {
+		t.Fatalf("test 4: received unexpected error: %s", err)
+	}
+	tt := testType{ID: 1, Data: "Data", Number: 5}
+	if err = s.Get(&tt); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if expected := (testType{ID: 1}); tt != expected {
+		t.Errorf("test 4: expecting %v, got %v", expected, tt)
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if ddl, err := s.PlanMigration(new(constrainedType)); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if expected := "CREATE TABLE IF NOT EXISTS [store.constrainedType]([ID] INTEGER PRIMARY KEY AUTOINCREMENT, [name] TEXT DEFAULT 'unknown' NOT NULL, [age] INTEGER CHECK ([age] >= 0), [Owner] INTEGER, FOREIGN KEY ([Owner]) REFERENCES [store.petOwner]([ID]) ON DELETE SET NULL);"; len(ddl) != 2 || ddl[1] != expected {
+		t.Fatalf("test 1: expecting DDL %q, got %q", expected, ddl)
+	}
+	if err = s.Register(new(constrainedType)); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	if err = s.Set(&constrainedType{Name: "Alice", Age: 30}); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	}
+	if err = s.Set(&constrainedType{Name: "Bob", Age: -1}); err == nil || !strings.Contains(err.Error(), "CHECK") {
+		t.Errorf("test 4: expecting CHECK constraint error, got %v", err)
+	}
+	if _, err = s.db.Exec("INSERT INTO [store.constrainedType] ([age]) VALUES (1);"); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if c, err := NewCollection[constrainedType](s); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if v, err := c.Get(2); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if v.Name != "unknown" {
+		t.Errorf("test 5: expecting default name %q, got %q", "unknown", v.Name)
+	}
+	if _, err = s.db.Exec("INSERT INTO [store.constrainedType] ([name], [age]) VALUES (NULL, 1);"); err == nil {
+		t.Errorf("test 6: expecting NOT NULL constraint error")
+	}
+	for n, i := range []interface{}{new(badNotNull), new(badNotNullCascade), new(badSliceOption), new(badMisspeltOption), new(badEmptyDefault)} {
+		if err = s.Register(i); err != ErrInvalidTag {
+			t.Errorf("test %d: expecting error %s, got %v", n+7, ErrInvalidTag, err)
+		}
+	}
+}
+
+func TestListedConstraints(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if ddl, err := s.PlanMigration(new(listedType)); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if expected := "CREATE TABLE IF NOT EXISTS [store.listedType]([ID] INTEGER PRIMARY KEY AUTOINCREMENT, [status] TEXT DEFAULT 'a,b' CHECK ([status] IN ('a,b', 'c')));"; len(ddl) != 1 || ddl[0] != expected {
+		t.Fatalf("test 1: expecting DDL %q, got %q", expected, ddl)
+	}
+	if err = s.Register(new(listedType)); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	if err = s.Set(&listedType{Status: "c"}); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	}
+	if err = s.Set(&listedType{Status: "a"}); err == nil || !strings.Contains(err.Error(), "CHECK") {
+		t.Errorf("test 4: expecting CHECK constraint error, got %v", err)
+	}
+}
+
+func TestMigrateConstraints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "constraints.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Set(&testType{Data: "Data"}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	s.Close()
+	type testType struct {
+		ID      int
+		Data    string
+		Number  int64
+		Status  string `store:"Status,notnull,default='new'"`
+		Comment *string
+	}
+	if s, err = New(path); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	defer s.Close()
+	expected := []string{
+		"ALTER TABLE [store.testType] ADD COLUMN [Status] TEXT DEFAULT 'new' NOT NULL;",
+		"ALTER TABLE [store.testType] ADD COLUMN [Comment] TEXT DEFAULT NULL;",
+	}
+	if ddl, err := s.PlanMigration(new(testType)); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(ddl, expected) {
+		t.Fatalf("test 1: expecting DDL %q, got %q", expected, ddl)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	tt := testType{ID: 1}
+	if err = s.Get(&tt); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if expected := (testType{ID: 1, Data: "Data", Status: "new"}); !reflect.DeepEqual(tt, expected) {
+		t.Errorf("test 2: expecting %v, got %v", expected, tt)
+	}
+}
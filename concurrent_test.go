@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrent(t *testing.T) {
+	for _, dsn := range []string{":memory:", filepath.Join(t.TempDir(), "concurrent.db")} {
+		s, err := New(dsn)
+		if err != nil {
+			t.Fatalf("%s: received unexpected error: %s", dsn, err)
+		}
+		defer s.Close()
+		if err = s.Register(new(testType)); err != nil {
+			t.Fatalf("%s: received unexpected error: %s", dsn, err)
+		}
+		const workers, records = 8, 20
+		var (
+			wg   sync.WaitGroup
+			errs = make(chan error, workers*4)
+		)
+		for w := 0; w < workers; w++ {
+			wg.Add(4)
+			go func(w int) {
+				defer wg.Done()
+				for n := 0; n < records; n++ {
+					if err := s.Set(&testType{0, fmt.Sprintf("%d-%d", w, n), int64(w)}); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(w)
+			go func(w int) {
+				defer wg.Done()
+				for n := 0; n < records; n++ {
+					tt := testType{ID: w*records + n + 1}
+					if err := s.Get(&tt); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(w)
+			go func(w int) {
+				defer wg.Done()
+				search := s.NewSearch(new(testType))
+				search.Filter = Eq("Number", int64(w))
+				p, err := search.Prepare()
+				if err != nil {
+					errs <- err
+					return
+				}
+				for n := 0; n < records; n++ {
+					if _, err := p.Count(); err != nil {
+						errs <- err
+						return
+					}
+					tts := make([]interface{}, 5)
+					for m := range tts {
+						tts[m] = new(testType)
+					}
+					if _, err := p.GetPage(tts, 0); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}(w)
+			go func() {
+				defer wg.Done()
+				for n := 0; n < records; n++ {
+					if _, err := s.Count(new(testType)); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("%s: received unexpected error: %s", dsn, err)
+		}
+		if n, err := s.Count(new(testType)); err != nil {
+			t.Errorf("%s: received unexpected error: %s", dsn, err)
+		} else if n != workers*records {
+			t.Errorf("%s: expecting %d records, got %d", dsn, workers*records, n)
+		}
+	}
+}
+
+func TestConcurrentReadDuringTx(t *testing.T) {
+	s, err := New(filepath.Join(t.TempDir(), "tx.db"))
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	defer s.Close()
+	var mode string
+	if err = s.db.QueryRow("PRAGMA journal_mode;").Scan(&mode); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if mode != "wal" {
+		t.Errorf("test 1: expecting journal mode wal, got %q", mode)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tt := testType{0, "One", 1}
+	if err = s.Set(&tt); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	defer tx.Rollback()
+	if err = tx.Set(&testType{0, "Two", 2}); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got := testType{ID: tt.ID}
+	if err = s.GetContext(ctx, &got); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if got != tt {
+		t.Errorf("test 3: expecting %v, got %v", tt, got)
+	}
+	if n, err := s.CountContext(ctx, new(testType)); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if n != 1 {
+		t.Errorf("test 4: expecting count 1, got %d", n)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = s.SetContext(ctx, &testType{0, "Three", 3}); err != context.DeadlineExceeded {
+		t.Errorf("test 5: expecting error %s, got %v", context.DeadlineExceeded, err)
+	}
+}
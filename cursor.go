@@ -0,0 +1,308 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+const cursorBatch = 100
+
+// Cursor streams the results of a PreparedSearch.
+//
+// Records are retrieved in batches, with each batch continuing on from the
+// sort column values of the last record of the previous one instead of using
+// an offset, so the cost of retrieving a batch does not grow with its depth.
+// For this to be stable, the sort columns should not contain NULL values.
+type Cursor struct {
+	search  *PreparedSearch
+	ctx     context.Context
+	typ     typeInfo
+	seek    []field
+	first   *sql.Stmt
+	next    *sql.Stmt
+	records []interface{}
+	last    []interface{}
+	pos     int
+	done    bool
+	err     error
+}
+
+func (p *PreparedSearch) Cursor() (*Cursor, error) {
+	return p.CursorContext(context.Background())
+}
+
+// CursorContext creates a Cursor over the results of the search. The context is
+// used for all batches retrieved by the Cursor.
+func (p *PreparedSearch) CursorContext(ctx context.Context) (*Cursor, error) {
+	p.store.typesMutex.RLock()
+	defer p.store.typesMutex.RUnlock()
+
+	prepare := p.store.db.PrepareContext
+
+	if p.tx != nil {
+		prepare = p.tx.PrepareContext
+	}
+
+	t := p.store.types[p.name]
+	c := &Cursor{
+		search: p,
+		ctx:    ctx,
+		typ:    t,
+	}
+
+	var (
+		order string
+		seek  []string
+		asc   []bool
+	)
+
+	sort := p.sort[:len(p.sort):len(p.sort)]
+
+	for n := range t.keys {
+		sort = append(sort, SortBy{Column: t.keyField(n).name, Asc: true})
+	}
+
+	for _, s := range sort {
+		f, ok := t.column(s.Column)
+		if !ok {
+			return nil, ErrUnknownColumn
+		}
+
+		c.seek = append(c.seek, f)
+		seek = append(seek, "["+f.name+"]")
+		asc = append(asc, s.Asc)
+
+		if order != "" {
+			order += ", "
+		}
+
+		order += "[" + f.name + "]"
+
+		if s.Asc {
+			order += " ASC"
+		} else {
+			order += " DESC"
+		}
+	}
+
+	var (
+		base  = "SELECT " + t.columns() + " FROM [" + p.name + "] "
+		where = "WHERE "
+		err   error
+	)
+
+	if p.filter != "" {
+		base += where + "(" + p.filter + ") "
+		where = "AND "
+	}
+
+	if c.first, err = prepare(ctx, rebind(p.store.dialect, base+"ORDER BY "+order+" LIMIT ?;")); err != nil {
+		return nil, err
+	}
+
+	if c.next, err = prepare(ctx, rebind(p.store.dialect, base+where+"("+seekCondition(seek, asc)+") ORDER BY "+order+" LIMIT ?;")); err != nil {
+		c.first.Close()
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func seekCondition(cols []string, asc []bool) string {
+	var (
+		cond string
+		eq   string
+	)
+
+	for n, col := range cols {
+		if n > 0 {
+			cond += " OR "
+		}
+
+		op := " < ?"
+		if asc[n] {
+			op = " > ?"
+		}
+
+		cond += "(" + eq + col + op + ")"
+		eq += col + " = ? AND "
+	}
+
+	return cond
+}
+
+// Next advances the Cursor to the next record, retrieving a new batch of
+// records when required. It returns false when there are no more records or an
+// error occurred, which can be checked with Err.
+func (c *Cursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+
+	if c.pos+1 < len(c.records) {
+		c.pos++
+
+		return true
+	}
+
+	if c.done {
+		c.records = nil
+
+		return false
+	}
+
+	if c.err = c.fetch(); c.err != nil {
+		c.records = nil
+
+		return false
+	}
+
+	c.pos = 0
+
+	return len(c.records) > 0
+}
+
+func (c *Cursor) fetch() error {
+	p := c.search
+
+	p.store.typesMutex.RLock()
+	defer p.store.typesMutex.RUnlock()
+
+	vars, err := p.getVars()
+	if err != nil {
+		return err
+	}
+
+	st := c.first
+
+	if c.last != nil {
+		st = c.next
+
+		for n := range c.last {
+			vars = append(vars, c.last[:n+1]...)
+		}
+	}
+
+	rows, err := st.QueryContext(c.ctx, append(vars, cursorBatch)...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	records := make([]interface{}, 0, cursorBatch)
+	refs := make([][]*interface{}, 0, cursorBatch)
+
+	for rows.Next() {
+		i := reflect.New(c.typ.typ).Interface()
+
+		ref, err := c.typ.scanRow(rows, i)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, i)
+		refs = append(refs, ref)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows.Close()
+
+	if len(records) < cursorBatch {
+		c.done = true
+	}
+
+	toGet, err := p.store.getRelated(c.ctx, p.tx, &c.typ, records, refs)
+	if err != nil {
+		return err
+	} else if len(toGet) > 0 {
+		if err = p.store.get(c.ctx, p.tx, loadOptions{depth: -1}.related(), toGet...); err != nil {
+			return err
+		}
+	}
+
+	if err = afterGet(c.ctx, records); err != nil {
+		return err
+	}
+
+	if len(records) > 0 {
+		if c.last, err = c.seekValues(records[len(records)-1]); err != nil {
+			return err
+		}
+	}
+
+	c.records = records
+
+	return nil
+}
+
+func (c *Cursor) seekValues(i interface{}) ([]interface{}, error) {
+	vals := make([]interface{}, len(c.seek))
+
+	for n, f := range c.seek {
+		if f.isStruct {
+			ni := getFieldPointer(i, f.pos)
+			if !reflect.ValueOf(ni).IsNil() {
+				nt := c.search.store.types[typeName(ni)]
+				vals[n] = nt.GetID(ni)[0]
+			}
+
+			continue
+		}
+
+		v, err := fieldValue(i, f)
+		if err != nil {
+			return nil, err
+		}
+
+		vals[n] = v
+	}
+
+	return vals, nil
+}
+
+// Scan copies the current record into the given pointer, which must be of the
+// searched type.
+func (c *Cursor) Scan(i interface{}) error {
+	if c.pos >= len(c.records) {
+		return ErrNotFound
+	}
+
+	v := reflect.ValueOf(i)
+	if v.Type() != reflect.PtrTo(c.typ.typ) || v.IsNil() {
+		return ErrInvalidType
+	}
+
+	v.Elem().Set(reflect.ValueOf(c.records[c.pos]).Elem())
+
+	return nil
+}
+
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+func (c *Cursor) Close() error {
+	c.done = true
+	c.records = nil
+
+	if c.first == nil {
+		return nil
+	}
+
+	err := c.first.Close()
+
+	if nerr := c.next.Close(); err == nil {
+		err = nerr
+	}
+
+	c.first = nil
+	c.next = nil
+
+	return err
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	values := make([]testType, 250)
+	is := make([]interface{}, len(values))
+	for n := range values {
+		values[n] = testType{0, fmt.Sprintf("%03d", (n*37)%250), int64(n % 7)}
+		is[n] = &values[n]
+	}
+	if err = s.Set(is...); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tests := []struct {
+		filter Filter
+		sort   []SortBy
+		keep   func(testType) bool
+		less   func(a, b testType) bool
+	}{
+		{
+			keep: func(testType) bool { return true },
+			less: func(a, b testType) bool { return a.ID < b.ID },
+		},
+		{
+			sort: []SortBy{{"Data", true}},
+			keep: func(testType) bool { return true },
+			less: func(a, b testType) bool { return a.Data < b.Data },
+		},
+		{
+			sort: []SortBy{{"Number", false}},
+			keep: func(testType) bool { return true },
+			less: func(a, b testType) bool {
+				if a.Number != b.Number {
+					return a.Number > b.Number
+				}
+				return a.ID < b.ID
+			},
+		},
+		{
+			filter: Or{Lt("Number", 2), Gt("Number", 5)},
+			sort:   []SortBy{{"Number", true}, {"Data", false}},
+			keep:   func(tt testType) bool { return tt.Number < 2 || tt.Number > 5 },
+			less: func(a, b testType) bool {
+				if a.Number != b.Number {
+					return a.Number < b.Number
+				}
+				return a.Data > b.Data
+			},
+		},
+		{
+			filter: Gt("Number", 10),
+			keep:   func(testType) bool { return false },
+		},
+	}
+	for n, test := range tests {
+		var expected []testType
+		for _, v := range values {
+			if test.keep(v) {
+				expected = append(expected, v)
+			}
+		}
+		if test.less != nil {
+			sort.Slice(expected, func(i, j int) bool { return test.less(expected[i], expected[j]) })
+		}
+		search := s.NewSearch(new(testType))
+		search.Filter = test.filter
+		search.Sort = test.sort
+		p, err := search.Prepare()
+		if err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+			continue
+		}
+		c, err := p.Cursor()
+		if err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+			continue
+		}
+		var got []testType
+		for c.Next() {
+			var tt testType
+			if err = c.Scan(&tt); err != nil {
+				t.Errorf("test %d: received unexpected error: %s", n+1, err)
+				break
+			}
+			got = append(got, tt)
+		}
+		if err = c.Err(); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if err = c.Close(); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("test %d: expecting %d records %v, got %d records %v", n+1, len(expected), expected, len(got), got)
+		}
+	}
+}
+
+func TestCursorScan(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(embeddedTestType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Set(&embeddedTestType{Data: "A", AnotherType: testType{Data: "Inner"}}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	p, err := s.NewSearch(new(embeddedTestType)).Prepare()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	c, err := p.Cursor()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	defer c.Close()
+	var tt embeddedTestType
+	if err = c.Scan(&tt); err != ErrNotFound {
+		t.Errorf("test 1: expecting error %s, got %v", ErrNotFound, err)
+	}
+	if !c.Next() {
+		t.Fatalf("test 2: expecting record, got error %v", c.Err())
+	}
+	if err = c.Scan(new(testType)); err != ErrInvalidType {
+		t.Errorf("test 3: expecting error %s, got %v", ErrInvalidType, err)
+	}
+	if err = c.Scan(&tt); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if expected := (embeddedTestType{ID: 1, Data: "A", AnotherType: testType{ID: 1, Data: "Inner"}}); !reflect.DeepEqual(tt, expected) {
+		t.Errorf("test 4: expecting %v, got %v", expected, tt)
+	}
+	if c.Next() {
+		t.Errorf("test 5: expecting no more records")
+	}
+}
+
+func TestCursorTx(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	defer tx.Rollback()
+	if err = tx.Set(&testType{0, "One", 1}, &testType{0, "Two", 2}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	p, err := tx.NewSearch(new(testType)).Prepare()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	c, err := p.Cursor()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	defer c.Close()
+	var got []testType
+	for c.Next() {
+		var tt testType
+		if err = c.Scan(&tt); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		got = append(got, tt)
+	}
+	if err = c.Err(); err != nil {
+		t.Errorf("received unexpected error: %s", err)
+	} else if expected := []testType{{1, "One", 1}, {2, "Two", 2}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expecting %v, got %v", expected, got)
+	}
+}
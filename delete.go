@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+const (
+	deleteOrphan uint8 = iota
+	deleteCascade
+	deleteRestrict
+	deleteSetNull
+)
+
+// deletePolicy parses the delete tag of a nested struct, or slice of structs,
+// which determines what happens when either end of the reference is removed.
+//
+// The default, orphan, leaves the nested records when the record is removed,
+// and leaves the reference as it is when a nested record is removed. Cascade
+// removes the nested records along with the record, and clears the reference
+// when a nested record is removed, as does set-null. Restrict prevents a
+// nested record being removed while it is referenced.
+func deletePolicy(tag string) (uint8, error) {
+	switch tag {
+	case "", "orphan":
+		return deleteOrphan, nil
+	case "cascade":
+		return deleteCascade, nil
+	case "restrict":
+		return deleteRestrict, nil
+	case "set-null":
+		return deleteSetNull, nil
+	}
+
+	return 0, ErrInvalidTag
+}
+
+func (s *Store) removeReferences(ctx context.Context, tx *sql.Tx, name string, id interface{}) error {
+	for pname, t := range s.types {
+		for _, f := range t.fields {
+			if f.ref != name || f.onDelete == deleteOrphan {
+				continue
+			}
+
+			where := " FROM [" + pname + "] WHERE [" + f.name + "] = ?;"
+
+			if err := s.removeReference(ctx, tx, f.onDelete, where, "UPDATE ["+pname+"] SET ["+f.name+"] = NULL WHERE ["+f.name+"] = ?;", id); err != nil {
+				return err
+			}
+		}
+
+		for _, c := range t.children {
+			if c.ref != name || c.onDelete == deleteOrphan {
+				continue
+			}
+
+			where := " FROM [" + c.table + "] WHERE [value] = ?;"
+
+			if err := s.removeReference(ctx, tx, c.onDelete, where, "DELETE"+where, id); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) removeReference(ctx context.Context, tx *sql.Tx, onDelete uint8, where, clear string, id interface{}) error {
+	if onDelete == deleteRestrict {
+		var n int
+
+		if err := tx.QueryRowContext(ctx, rebind(s.dialect, "SELECT COUNT(1)"+where), id).Scan(&n); err != nil {
+			return err
+		} else if n > 0 {
+			return ErrReferenced
+		}
+
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, rebind(s.dialect, clear), id)
+
+	return err
+}
+
+func (s *Store) cascades(ctx context.Context, tx *sql.Tx, name string, t *typeInfo, key []interface{}) ([]interface{}, error) {
+	var cascade []interface{}
+
+	for _, f := range t.fields {
+		if f.onDelete != deleteCascade || !f.isStruct {
+			continue
+		}
+
+		var ref interface{}
+
+		if err := tx.QueryRowContext(ctx, rebind(s.dialect, "SELECT ["+f.name+"] FROM ["+name+"] WHERE "+t.keyWhere()+";"), key...).Scan(&ref); err == sql.ErrNoRows {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		} else if ref != nil {
+			i, err := s.newRef(f.ref, ref)
+			if err != nil {
+				return nil, err
+			}
+
+			cascade = append(cascade, i)
+		}
+	}
+
+	for _, c := range t.children {
+		if c.onDelete != deleteCascade || !c.isStruct {
+			continue
+		}
+
+		rows, err := stmt(tx, c.statements[childGet]).QueryContext(ctx, key[0])
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var (
+				ref interface{}
+				i   interface{}
+			)
+
+			if err = rows.Scan(&ref); err != nil {
+				break
+			} else if i, err = s.newRef(c.ref, ref); err != nil {
+				break
+			}
+
+			cascade = append(cascade, i)
+		}
+
+		rows.Close()
+
+		if err != nil {
+			return nil, err
+		} else if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return cascade, nil
+}
+
+func (s *Store) newRef(name string, id interface{}) (interface{}, error) {
+	t := s.types[name]
+	i := reflect.New(t.typ).Interface()
+
+	return i, t.SetID(i, []interface{}{id})
+}
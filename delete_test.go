@@ -0,0 +1,153 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+type petOwner struct {
+	ID   int
+	Name string
+}
+
+type petVet struct {
+	ID   int
+	Name string
+}
+
+type petTag struct {
+	ID     int
+	Number int
+}
+
+type pet struct {
+	ID    int
+	Name  string
+	Owner *petOwner `delete:"restrict"`
+	Vet   *petVet   `delete:"set-null"`
+	Tag   petTag    `delete:"cascade"`
+	Toys  []petTag  `delete:"cascade"`
+}
+
+type petLicence struct {
+	ID      int
+	Owner   *petOwner `store:"owner,notnull"`
+	Vets    []petVet
+	Renewed []petVet `delete:"set-null"`
+}
+
+func TestDeletePolicies(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(pet)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	var schema string
+	if err = s.db.QueryRow("SELECT [sql] FROM [sqlite_master] WHERE [name] = 'store.pet';").Scan(&schema); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	for _, fk := range []string{
+		"FOREIGN KEY ([Owner]) REFERENCES [store.petOwner]([ID]) ON DELETE RESTRICT",
+		"FOREIGN KEY ([Vet]) REFERENCES [store.petVet]([ID]) ON DELETE SET NULL",
+		"FOREIGN KEY ([Tag]) REFERENCES [store.petTag]([ID]) ON DELETE SET NULL",
+	} {
+		if !strings.Contains(schema, fk) {
+			t.Errorf("expecting schema to contain %q, got %q", fk, schema)
+		}
+	}
+	p := pet{
+		Name:  "Rex",
+		Owner: &petOwner{Name: "Alice"},
+		Vet:   &petVet{Name: "Bob"},
+		Tag:   petTag{Number: 42},
+		Toys:  []petTag{{Number: 1}, {Number: 2}},
+	}
+	if err = s.Set(&p); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if err = s.Remove(p.Owner); err != ErrReferenced {
+		t.Errorf("test 2: expecting error %s, got %v", ErrReferenced, err)
+	}
+	if err = s.Remove(p.Vet); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	}
+	got := pet{ID: p.ID}
+	if err = s.Get(&got); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if got.Vet != nil {
+		t.Errorf("test 4: expecting nil vet, got %v", got.Vet)
+	} else if got.Owner == nil || *got.Owner != *p.Owner {
+		t.Errorf("test 4: expecting owner %v, got %v", p.Owner, got.Owner)
+	}
+	if err = s.Remove(&pet{ID: p.ID}); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	}
+	if n, err := s.Count(new(petTag)); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if n != 0 {
+		t.Errorf("test 6: expecting 0 tags, got %d", n)
+	}
+	if n, err := s.Count(new(petOwner)); err != nil {
+		t.Errorf("test 7: received unexpected error: %s", err)
+	} else if n != 1 {
+		t.Errorf("test 7: expecting 1 owner, got %d", n)
+	}
+	if err = s.Remove(p.Owner); err != nil {
+		t.Errorf("test 8: received unexpected error: %s", err)
+	}
+	if err = s.Register(new(struct {
+		ID    int
+		Owner petOwner `delete:"explode"`
+	})); err != ErrInvalidTag {
+		t.Errorf("test 9: expecting error %s, got %v", ErrInvalidTag, err)
+	}
+}
+
+func TestDeleteOrphan(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(petLicence)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	var schema string
+	if err = s.db.QueryRow("SELECT [sql] FROM [sqlite_master] WHERE [name] = 'store.petLicence';").Scan(&schema); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	} else if strings.Contains(schema, "FOREIGN KEY") {
+		t.Errorf("expecting no foreign keys, got %q", schema)
+	}
+	l := petLicence{Owner: &petOwner{Name: "Alice"}, Vets: []petVet{{Name: "Bob"}}, Renewed: []petVet{{Name: "Carol"}}}
+	if err = s.Set(&l); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if err = s.Remove(l.Owner); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if err = s.Remove(&l.Vets[0], &l.Renewed[0]); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	}
+	for n, test := range []struct {
+		query    string
+		expected int
+	}{
+		{"SELECT COUNT(1) FROM [store.petLicence] WHERE [owner] = 1;", 1},
+		{"SELECT COUNT(1) FROM [store.petLicence.Vets];", 1},
+		{"SELECT COUNT(1) FROM [store.petLicence.Renewed];", 0},
+	} {
+		var count int
+		if err = s.db.QueryRow(test.query).Scan(&count); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+3, err)
+		} else if count != test.expected {
+			t.Errorf("test %d: expecting %d rows, got %d", n+3, test.expected, count)
+		}
+	}
+	if err = s.Remove(&l); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if n, err := s.Count(new(petLicence)); err != nil || n != 0 {
+		t.Errorf("test 6: expecting no licences, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,466 @@
+package store
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect converts the generic SQL generated by the Store into that understood
+// by a specific database.
+//
+// Internally, identifiers are surrounded by square brackets and parameters are
+// denoted by question marks; these are passed to Quote and Placeholder
+// respectively to be rewritten.
+type Dialect interface {
+	// Quote returns the quoted form of the given identifier.
+	Quote(identifier string) string
+	// Placeholder returns the parameter placeholder for the nth (1-indexed)
+	// parameter.
+	Placeholder(n int) string
+	// ColumnType converts a generic column type (BOOLEAN, INTEGER, FLOAT,
+	// TEXT, BLOB) to the type used by the database. Text and binary columns
+	// that are part of a key, index or reference are instead given the types
+	// VARCHAR and VARBINARY, for databases that can only index columns of a
+	// limited length.
+	ColumnType(sqlType string) string
+	// LiteralDefault returns true if a column of the given database type,
+	// as returned by ColumnType, can be given a literal default value.
+	LiteralDefault(columnType string) bool
+	// AutoIncrementKey returns the full column definition for an
+	// auto-incrementing primary key of the given generic type.
+	AutoIncrementKey(sqlType string) string
+	// DefaultValues returns the clause, following the table name, of an
+	// INSERT statement that gives every column its default value.
+	DefaultValues() string
+	// ReturningID returns true when inserted keys are to be retrieved with a
+	// RETURNING clause instead of sql.Result.LastInsertId.
+	ReturningID() bool
+	// Columns returns the columns, in order, of the named table, returning
+	// no columns if the table does not exist.
+	Columns(db *sql.DB, table string) ([]Column, error)
+	// RenameColumn returns the generic statement that renames a column, or
+	// an empty string when the table must instead be rebuilt.
+	RenameColumn(table, from, to string) string
+	// Upsert returns the generic statement that inserts a record, taking the
+	// key columns followed by the other columns as parameters, updating the
+	// existing record when the key is already in use. An empty string can be
+	// returned when the record must instead be updated, and inserted if not
+	// found.
+	Upsert(table string, keys, columns []string) string
+	// Indexes returns the names of the existing indexes on the named table.
+	Indexes(db *sql.DB, table string) ([]string, error)
+	// UniqueViolation returns true if the given error was caused by a unique
+	// constraint, along with the name of the index, or, for databases that
+	// report the columns instead, the comma separated list of table qualified
+	// columns.
+	UniqueViolation(err error) (string, bool)
+}
+
+// Column describes an existing column in a database table.
+type Column struct {
+	Name, Type string
+}
+
+// Dialects for supported databases.
+var (
+	SQLite     Dialect = sqlite{}
+	PostgreSQL Dialect = postgres{}
+	MySQL      Dialect = mysql{}
+)
+
+type sqlite struct{}
+
+func (sqlite) Quote(identifier string) string {
+	return "[" + identifier + "]"
+}
+
+func (sqlite) Placeholder(int) string {
+	return "?"
+}
+
+func (sqlite) ColumnType(sqlType string) string {
+	switch sqlType {
+	case "VARCHAR":
+		return "TEXT"
+	case "VARBINARY":
+		return "BLOB"
+	}
+
+	return sqlType
+}
+
+func (sqlite) LiteralDefault(string) bool {
+	return true
+}
+
+func (sqlite) AutoIncrementKey(sqlType string) string {
+	return sqlType + " PRIMARY KEY AUTOINCREMENT"
+}
+
+func (sqlite) DefaultValues() string {
+	return "DEFAULT VALUES"
+}
+
+func (sqlite) ReturningID() bool {
+	return false
+}
+
+func (sqlite) Columns(db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.Query("PRAGMA table_info([" + table + "]);")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var (
+		columns []Column
+		cid, pk int
+		notNull bool
+		def     interface{}
+	)
+
+	for rows.Next() {
+		var c Column
+
+		if err := rows.Scan(&cid, &c.Name, &c.Type, &notNull, &def, &pk); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, c)
+	}
+
+	return columns, rows.Err()
+}
+
+func (sqlite) RenameColumn(string, string, string) string {
+	return ""
+}
+
+// The bundled SQLite library predates support for ON CONFLICT clauses on
+// INSERT statements.
+func (sqlite) Upsert(string, []string, []string) string {
+	return ""
+}
+
+func (sqlite) Indexes(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query("PRAGMA index_list([" + table + "]);")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil || len(cols) < 2 {
+		return nil, err
+	}
+
+	var (
+		indexes []string
+		name    string
+		vars    = make([]interface{}, len(cols))
+	)
+
+	for n := range vars {
+		vars[n] = new(interface{})
+	}
+
+	vars[1] = &name
+
+	for rows.Next() {
+		if err := rows.Scan(vars...); err != nil {
+			return nil, err
+		}
+
+		indexes = append(indexes, name)
+	}
+
+	return indexes, rows.Err()
+}
+
+func (sqlite) UniqueViolation(err error) (string, bool) {
+	return constraintName(err, "UNIQUE constraint failed: ", " [")
+}
+
+type postgres struct{}
+
+func (postgres) Quote(identifier string) string {
+	return "\"" + strings.ReplaceAll(identifier, "\"", "\"\"") + "\""
+}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgres) ColumnType(sqlType string) string {
+	switch sqlType {
+	case "INTEGER":
+		return "BIGINT"
+	case "FLOAT":
+		return "DOUBLE PRECISION"
+	case "BLOB", "VARBINARY":
+		return "BYTEA"
+	case "VARCHAR":
+		return "TEXT"
+	}
+
+	return sqlType
+}
+
+func (postgres) LiteralDefault(string) bool {
+	return true
+}
+
+func (postgres) AutoIncrementKey(string) string {
+	return "BIGSERIAL PRIMARY KEY"
+}
+
+func (postgres) DefaultValues() string {
+	return "DEFAULT VALUES"
+}
+
+func (postgres) ReturningID() bool {
+	return true
+}
+
+func (postgres) Columns(db *sql.DB, table string) ([]Column, error) {
+	return queryColumns(db, "SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1 ORDER BY ordinal_position;", table)
+}
+
+func (postgres) RenameColumn(table, from, to string) string {
+	return renameColumn(table, from, to)
+}
+
+func (postgres) Upsert(table string, keys, columns []string) string {
+	sql := insertKey(table, keys, columns) + " ON CONFLICT ([" + strings.Join(keys, "], [") + "]) DO "
+
+	if len(columns) == 0 {
+		return sql + "NOTHING;"
+	}
+
+	sql += "UPDATE SET "
+
+	for n, c := range columns {
+		if n > 0 {
+			sql += ", "
+		}
+
+		sql += "[" + c + "] = EXCLUDED.[" + c + "]"
+	}
+
+	return sql + ";"
+}
+
+func (postgres) Indexes(db *sql.DB, table string) ([]string, error) {
+	return queryNames(db, "SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = $1;", table)
+}
+
+func (postgres) UniqueViolation(err error) (string, bool) {
+	return constraintName(err, "violates unique constraint \"", "\"")
+}
+
+// mysqlKeyLength is the length of indexed text and binary columns, which, for
+// four byte characters, keeps a column within the limit on the size of an
+// index key.
+const mysqlKeyLength = 255
+
+type mysql struct{}
+
+func (mysql) Quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func (mysql) Placeholder(int) string {
+	return "?"
+}
+
+func (mysql) ColumnType(sqlType string) string {
+	switch sqlType {
+	case "INTEGER":
+		return "BIGINT"
+	case "FLOAT":
+		return "DOUBLE"
+	case "BOOLEAN":
+		return "TINYINT"
+	case "VARCHAR", "VARBINARY":
+		return sqlType + "(" + strconv.Itoa(mysqlKeyLength) + ")"
+	}
+
+	return sqlType
+}
+
+func (mysql) LiteralDefault(columnType string) bool {
+	return columnType != "TEXT" && columnType != "BLOB"
+}
+
+func (mysql) AutoIncrementKey(string) string {
+	return "BIGINT PRIMARY KEY AUTO_INCREMENT"
+}
+
+func (mysql) DefaultValues() string {
+	return "() VALUES ()"
+}
+
+func (mysql) ReturningID() bool {
+	return false
+}
+
+func (mysql) Columns(db *sql.DB, table string) ([]Column, error) {
+	return queryColumns(db, "SELECT COLUMN_NAME, DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION;", table)
+}
+
+func (mysql) RenameColumn(table, from, to string) string {
+	return renameColumn(table, from, to)
+}
+
+func (mysql) Upsert(table string, keys, columns []string) string {
+	sql := insertKey(table, keys, columns) + " ON DUPLICATE KEY UPDATE "
+
+	if len(columns) == 0 {
+		return sql + "[" + keys[0] + "] = [" + keys[0] + "];"
+	}
+
+	for n, c := range columns {
+		if n > 0 {
+			sql += ", "
+		}
+
+		sql += "[" + c + "] = VALUES([" + c + "])"
+	}
+
+	return sql + ";"
+}
+
+func (mysql) Indexes(db *sql.DB, table string) ([]string, error) {
+	return queryNames(db, "SELECT DISTINCT INDEX_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?;", table)
+}
+
+func (mysql) UniqueViolation(err error) (string, bool) {
+	return constraintName(err, "' for key '", "'")
+}
+
+func queryColumns(db *sql.DB, query, table string) ([]Column, error) {
+	rows, err := db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var columns []Column
+
+	for rows.Next() {
+		var c Column
+
+		if err := rows.Scan(&c.Name, &c.Type); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, c)
+	}
+
+	return columns, rows.Err()
+}
+
+func queryNames(db *sql.DB, query, table string) ([]string, error) {
+	rows, err := db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var names []string
+
+	for rows.Next() {
+		var name string
+
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// constraintName extracts the text between the given prefix and the next
+// occurrence of end from an error message, or the rest of the message if end
+// does not occur.
+func constraintName(err error, prefix, end string) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	msg := err.Error()
+
+	start := strings.Index(msg, prefix)
+	if start == -1 {
+		return "", false
+	}
+
+	msg = msg[start+len(prefix):]
+
+	if stop := strings.Index(msg, end); stop != -1 {
+		msg = msg[:stop]
+	}
+
+	return msg, true
+}
+
+func insertKey(table string, keys, columns []string) string {
+	columns = append(keys[:len(keys):len(keys)], columns...)
+
+	return "INSERT INTO [" + table + "] ([" + strings.Join(columns, "], [") + "]) VALUES (" + placeholders(len(columns)) + ")"
+}
+
+func renameColumn(table, from, to string) string {
+	return "ALTER TABLE [" + table + "] RENAME COLUMN [" + from + "] TO [" + to + "];"
+}
+
+func rebind(d Dialect, sql string) string {
+	var (
+		sb strings.Builder
+		n  int
+	)
+
+	for len(sql) > 0 {
+		switch c := sql[0]; c {
+		case '\'':
+			end := strings.IndexByte(sql[1:], '\'') + 2
+			if end == 1 {
+				end = len(sql)
+			}
+
+			sb.WriteString(sql[:end])
+
+			sql = sql[end:]
+		case '[':
+			end := strings.IndexByte(sql, ']')
+			if end == -1 {
+				sb.WriteString(sql)
+
+				return sb.String()
+			}
+
+			sb.WriteString(d.Quote(sql[1:end]))
+
+			sql = sql[end+1:]
+		case '?':
+			n++
+
+			sb.WriteString(d.Placeholder(n))
+
+			sql = sql[1:]
+		default:
+			sb.WriteByte(c)
+
+			sql = sql[1:]
+		}
+	}
+
+	return sb.String()
+}
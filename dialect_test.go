@@ -0,0 +1,158 @@
+package store
+
+import "testing"
+
+func TestDialectSQL(t *testing.T) {
+	fields := []field{
+		{pos: 0, name: "ID"},
+		{pos: 1, name: "Data"},
+		{pos: 2, name: "Number"},
+	}
+	for n, test := range []struct {
+		dialect Dialect
+		create  string
+		queries []string
+	}{
+		{
+			SQLite,
+			"CREATE TABLE IF NOT EXISTS [store.testType]([ID] INTEGER PRIMARY KEY AUTOINCREMENT, [Data] TEXT, [Number] INTEGER);",
+			[]string{
+				"INSERT INTO [store.testType] ([Data], [Number]) VALUES (?, ?);",
+				"SELECT [ID], [Data], [Number] FROM [store.testType] WHERE [ID] = ? LIMIT 1;",
+				"UPDATE [store.testType] SET [Data] = ?, [Number] = ? WHERE [ID] = ?;",
+				"DELETE FROM [store.testType] WHERE [ID] = ?;",
+				"SELECT [ID], [Data], [Number] FROM [store.testType] ORDER BY [ID] LIMIT ? OFFSET ?;",
+				"SELECT COUNT(1) FROM [store.testType];",
+				"INSERT INTO [store.testType] ([ID], [Data], [Number]) VALUES (?, ?, ?);",
+				"",
+				"SELECT 1 FROM [store.testType] WHERE [ID] = ? LIMIT 1;",
+				"",
+				"",
+			},
+		},
+		{
+			PostgreSQL,
+			"CREATE TABLE IF NOT EXISTS \"store.testType\"(\"ID\" BIGSERIAL PRIMARY KEY, \"Data\" TEXT, \"Number\" BIGINT);",
+			[]string{
+				"INSERT INTO \"store.testType\" (\"Data\", \"Number\") VALUES ($1, $2) RETURNING \"ID\";",
+				"SELECT \"ID\", \"Data\", \"Number\" FROM \"store.testType\" WHERE \"ID\" = $1 LIMIT 1;",
+				"UPDATE \"store.testType\" SET \"Data\" = $1, \"Number\" = $2 WHERE \"ID\" = $3;",
+				"DELETE FROM \"store.testType\" WHERE \"ID\" = $1;",
+				"SELECT \"ID\", \"Data\", \"Number\" FROM \"store.testType\" ORDER BY \"ID\" LIMIT $1 OFFSET $2;",
+				"SELECT COUNT(1) FROM \"store.testType\";",
+				"INSERT INTO \"store.testType\" (\"ID\", \"Data\", \"Number\") VALUES ($1, $2, $3);",
+				"INSERT INTO \"store.testType\" (\"ID\", \"Data\", \"Number\") VALUES ($1, $2, $3) ON CONFLICT (\"ID\") DO UPDATE SET \"Data\" = EXCLUDED.\"Data\", \"Number\" = EXCLUDED.\"Number\";",
+				"SELECT 1 FROM \"store.testType\" WHERE \"ID\" = $1 LIMIT 1;",
+				"",
+				"",
+			},
+		},
+		{
+			MySQL,
+			"CREATE TABLE IF NOT EXISTS `store.testType`(`ID` BIGINT PRIMARY KEY AUTO_INCREMENT, `Data` TEXT, `Number` BIGINT);",
+			[]string{
+				"INSERT INTO `store.testType` (`Data`, `Number`) VALUES (?, ?);",
+				"SELECT `ID`, `Data`, `Number` FROM `store.testType` WHERE `ID` = ? LIMIT 1;",
+				"UPDATE `store.testType` SET `Data` = ?, `Number` = ? WHERE `ID` = ?;",
+				"DELETE FROM `store.testType` WHERE `ID` = ?;",
+				"SELECT `ID`, `Data`, `Number` FROM `store.testType` ORDER BY `ID` LIMIT ? OFFSET ?;",
+				"SELECT COUNT(1) FROM `store.testType`;",
+				"INSERT INTO `store.testType` (`ID`, `Data`, `Number`) VALUES (?, ?, ?);",
+				"INSERT INTO `store.testType` (`ID`, `Data`, `Number`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `Data` = VALUES(`Data`), `Number` = VALUES(`Number`);",
+				"SELECT 1 FROM `store.testType` WHERE `ID` = ? LIMIT 1;",
+				"",
+				"",
+			},
+		},
+	} {
+		create, queries := buildSQL(test.dialect, "store.testType", new(testType), fields, []int{0}, true)
+		if create != test.create {
+			t.Errorf("test %d: expecting create SQL %q, got %q", n+1, test.create, create)
+		}
+		if len(queries) != len(test.queries) {
+			t.Errorf("test %d: expecting %d queries, got %d", n+1, len(test.queries), len(queries))
+			continue
+		}
+		for m, query := range queries {
+			if query != test.queries[m] {
+				t.Errorf("test %d-%d: expecting SQL %q, got %q", n+1, m+1, test.queries[m], query)
+			}
+		}
+	}
+}
+
+type dialectKeyed struct {
+	Slug   string `key:"1"`
+	Email  string `store:"email,unique"`
+	Name   string `index:"name"`
+	Bio    string
+	Parent *auditedKey
+}
+
+func TestDialectIndexedTypes(t *testing.T) {
+	fields := []field{
+		{pos: 0, name: "Slug"},
+		{pos: 1, name: "email", unique: true},
+		{pos: 2, name: "Name", indexes: []string{"name"}},
+		{pos: 3, name: "Bio"},
+		{pos: 4, name: "Parent", isStruct: true, ref: "store.auditedKey", refKey: "Slug", refType: "TEXT", onDelete: deleteSetNull},
+	}
+	for n, test := range []struct {
+		dialect      Dialect
+		create, tags string
+	}{
+		{
+			SQLite,
+			"CREATE TABLE IF NOT EXISTS [store.dialectKeyed]([Slug] TEXT, [email] TEXT, [Name] TEXT, [Bio] TEXT, [Parent] TEXT, PRIMARY KEY ([Slug]), FOREIGN KEY ([Parent]) REFERENCES [store.auditedKey]([Slug]) ON DELETE SET NULL);",
+			"CREATE TABLE IF NOT EXISTS [store.dialectKeyed.Tags]([parent] TEXT, [pos] INTEGER, [value] TEXT, PRIMARY KEY ([parent], [pos]));",
+		},
+		{
+			PostgreSQL,
+			"CREATE TABLE IF NOT EXISTS \"store.dialectKeyed\"(\"Slug\" TEXT, \"email\" TEXT, \"Name\" TEXT, \"Bio\" TEXT, \"Parent\" TEXT, PRIMARY KEY (\"Slug\"), FOREIGN KEY (\"Parent\") REFERENCES \"store.auditedKey\"(\"Slug\") ON DELETE SET NULL);",
+			"CREATE TABLE IF NOT EXISTS \"store.dialectKeyed.Tags\"(\"parent\" TEXT, \"pos\" BIGINT, \"value\" TEXT, PRIMARY KEY (\"parent\", \"pos\"));",
+		},
+		{
+			MySQL,
+			"CREATE TABLE IF NOT EXISTS `store.dialectKeyed`(`Slug` VARCHAR(255), `email` VARCHAR(255), `Name` VARCHAR(255), `Bio` TEXT, `Parent` VARCHAR(255), PRIMARY KEY (`Slug`), FOREIGN KEY (`Parent`) REFERENCES `store.auditedKey`(`Slug`) ON DELETE SET NULL);",
+			"CREATE TABLE IF NOT EXISTS `store.dialectKeyed.Tags`(`parent` VARCHAR(255), `pos` BIGINT, `value` TEXT, PRIMARY KEY (`parent`, `pos`));",
+		},
+	} {
+		if create, queries := buildSQL(test.dialect, "store.dialectKeyed", new(dialectKeyed), fields, []int{0}, false); create != test.create {
+			t.Errorf("test %d: expecting create SQL %q, got %q", n+1, test.create, create)
+		} else if queries[upsert] != "" {
+			t.Errorf("test %d: expecting no upsert SQL with a unique column, got %q", n+1, queries[upsert])
+		}
+		if tags, _ := buildChildSQL(test.dialect, "store.dialectKeyed.Tags", "TEXT", "TEXT"); tags != test.tags {
+			t.Errorf("test %d: expecting child SQL %q, got %q", n+1, test.tags, tags)
+		}
+	}
+	for n, test := range []struct {
+		typ, expected string
+		same          bool
+	}{
+		{"varchar", "VARCHAR(255)", true},
+		{"VARCHAR(255)", "VARCHAR(255)", true},
+		{"VARCHAR(100)", "VARCHAR(255)", false},
+		{"text", "VARCHAR(255)", false},
+		{"bigint", "BIGINT", true},
+	} {
+		if same := sameColumnType(test.typ, test.expected); same != test.same {
+			t.Errorf("test %d: expecting %v comparing %q with %q, got %v", n+4, test.same, test.typ, test.expected, same)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	for n, test := range []struct {
+		dialect  Dialect
+		sql, out string
+	}{
+		{SQLite, "SELECT [a] FROM [b] WHERE [c] = ? AND [d] = 'x?[y]';", "SELECT [a] FROM [b] WHERE [c] = ? AND [d] = 'x?[y]';"},
+		{PostgreSQL, "SELECT [a] FROM [b] WHERE [c] = ? AND [d] = 'x?[y]' AND [e\"] IN (?, ?);", "SELECT \"a\" FROM \"b\" WHERE \"c\" = $1 AND \"d\" = 'x?[y]' AND \"e\"\"\" IN ($2, $3);"},
+		{MySQL, "SELECT [a`] FROM [b] WHERE [c] = 'it''s' OR [c] = ?;", "SELECT `a``` FROM `b` WHERE `c` = 'it''s' OR `c` = ?;"},
+	} {
+		if out := rebind(test.dialect, test.sql); out != test.out {
+			t.Errorf("test %d: expecting %q, got %q", n+1, test.out, out)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Event describes a change to a record, identified by the name of its type
+// and its key.
+type Event struct {
+	Type      string
+	Operation Operation
+	Key       []interface{}
+}
+
+type subscription struct {
+	fn     func(Event)
+	mu     sync.Mutex
+	queue  []Event
+	signal chan struct{}
+	done   chan struct{}
+}
+
+func (s *subscription) send(e Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.signal:
+		}
+
+		s.mu.Lock()
+		events := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, e := range events {
+			select {
+			case <-s.done:
+				return
+			default:
+				s.fn(e)
+			}
+		}
+	}
+}
+
+// Subscribe calls the given func with an Event for each record of the type of
+// the given record that is inserted, updated or removed, including as a
+// nested struct or by a cascade delete policy, once the change has been
+// committed.
+//
+// Events are delivered in order on a separate goroutine for each
+// subscription, so that a slow subscriber does not block writes or other
+// subscribers. The returned func unsubscribes, after which at most one event
+// that is already being delivered will be.
+func (s *Store) Subscribe(i interface{}, fn func(Event)) (func(), error) {
+	if !isPointerStruct(i) {
+		return nil, ErrNoPointerStruct
+	}
+
+	name := typeName(i)
+
+	s.typesMutex.RLock()
+	_, ok := s.types[name]
+	s.typesMutex.RUnlock()
+
+	if !ok {
+		return nil, ErrUnregisteredType
+	}
+
+	sub := &subscription{
+		fn:     fn,
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	s.subsMutex.Lock()
+	defer s.subsMutex.Unlock()
+
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string][]*subscription)
+	}
+
+	s.subscriptions[name] = append(s.subscriptions[name], sub)
+
+	go sub.run()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			s.unsubscribe(name, sub)
+		})
+	}, nil
+}
+
+func (s *Store) unsubscribe(name string, sub *subscription) {
+	s.subsMutex.Lock()
+	defer s.subsMutex.Unlock()
+
+	subs := s.subscriptions[name]
+
+	for n, su := range subs {
+		if su == sub {
+			s.subscriptions[name] = append(subs[:n:n], subs[n+1:]...)
+
+			close(sub.done)
+
+			break
+		}
+	}
+}
+
+func (s *Store) unsubscribeAll() {
+	s.subsMutex.Lock()
+	defer s.subsMutex.Unlock()
+
+	for _, subs := range s.subscriptions {
+		for _, sub := range subs {
+			close(sub.done)
+		}
+	}
+
+	s.subscriptions = nil
+	s.pending = nil
+}
+
+func (s *Store) subscribed(t *typeInfo) bool {
+	s.subsMutex.Lock()
+	defer s.subsMutex.Unlock()
+
+	return len(s.subscriptions[t.typ.String()]) > 0
+}
+
+// changed records a change to a record in its history, if audited, and queues
+// an event for its subscribers and the caches, which is sent once the change
+// is committed.
+//
+// Changes are only made while holding the Store lock, so there is only ever
+// one transaction queueing events.
+func (s *Store) changed(ctx context.Context, tx *sql.Tx, t *typeInfo, op Operation, key, old []interface{}) error {
+	if err := s.audit(ctx, tx, t, op, key, old); err != nil {
+		return err
+	}
+
+	if t.cache != nil || op == Deleted || s.subscribed(t) {
+		s.subsMutex.Lock()
+		s.pending = append(s.pending, Event{Type: t.typ.String(), Operation: op, Key: key})
+		s.subsMutex.Unlock()
+	}
+
+	return nil
+}
+
+// upsertOperation determines whether an upsert of a record will insert or
+// update it, only checking when there are subscribers to be told.
+func (s *Store) upsertOperation(ctx context.Context, tx *sql.Tx, t *typeInfo, key []interface{}) (Operation, error) {
+	if !s.subscribed(t) {
+		return Updated, nil
+	}
+
+	found, err := s.recordExists(ctx, tx, t, key)
+	if err != nil || found {
+		return Updated, err
+	}
+
+	return Inserted, nil
+}
+
+// publish sends the queued events to the caches and subscribers after a
+// commit, or discards them after a rollback.
+func (s *Store) publish(committed bool) {
+	s.subsMutex.Lock()
+	defer s.subsMutex.Unlock()
+
+	if committed {
+		for _, e := range s.pending {
+			s.invalidateCaches(e)
+
+			for _, sub := range s.subscriptions[e.Type] {
+				sub.send(e)
+			}
+		}
+	}
+
+	s.pending = nil
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type eventChild struct {
+	ID   int
+	Name string
+}
+
+type eventParent struct {
+	ID    int
+	Child eventChild `delete:"cascade"`
+}
+
+type eventKeyed struct {
+	Slug string `key:"1"`
+	Name string
+}
+
+func receiveEvents(ch chan Event, n int) []Event {
+	var events []Event
+
+	for len(events) < n {
+		select {
+		case e := <-ch:
+			events = append(events, e)
+		case <-time.After(time.Second):
+			return events
+		}
+	}
+
+	select {
+	case e := <-ch:
+		events = append(events, e)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	return events
+}
+
+func TestSubscribe(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(eventParent), new(eventKeyed)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	children, parents := make(chan Event, 10), make(chan Event, 10)
+	unsubscribe, err := s.Subscribe(new(eventChild), func(e Event) { children <- e })
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if _, err = s.Subscribe(new(eventParent), func(e Event) { parents <- e }); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	keyed := make(chan Event, 10)
+	if _, err = s.Subscribe(new(eventKeyed), func(e Event) { keyed <- e }); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	p := eventParent{Child: eventChild{Name: "A"}}
+	for n, test := range []struct {
+		fn       func() error
+		ch       chan Event
+		expected []Event
+	}{
+		{
+			func() error { return s.Set(&p) },
+			children,
+			[]Event{{"store.eventChild", Inserted, []interface{}{int64(1)}}},
+		},
+		{
+			func() error { return nil },
+			parents,
+			[]Event{{"store.eventParent", Inserted, []interface{}{int64(1)}}},
+		},
+		{
+			func() error { p.Child.Name = "B"; return s.Set(&p) },
+			children,
+			[]Event{{"store.eventChild", Updated, []interface{}{int64(1)}}},
+		},
+		{
+			func() error { return s.Set(&eventKeyed{Slug: "a"}, &eventKeyed{Slug: "a", Name: "A"}) },
+			keyed,
+			[]Event{{"store.eventKeyed", Inserted, []interface{}{"a"}}, {"store.eventKeyed", Updated, []interface{}{"a"}}},
+		},
+		{
+			func() error {
+				tx, err := s.Begin()
+				if err != nil {
+					return err
+				} else if err = tx.Set(&eventKeyed{Slug: "b"}); err != nil {
+					return err
+				}
+				return tx.Rollback()
+			},
+			keyed,
+			nil,
+		},
+		{
+			func() error { return s.Insert(&eventKeyed{Slug: "b"}, &eventKeyed{Slug: "a"}) },
+			keyed,
+			nil,
+		},
+		{
+			func() error {
+				tx, err := s.Begin()
+				if err != nil {
+					return err
+				} else if err = tx.Set(&eventKeyed{Slug: "c"}); err != nil {
+					return err
+				} else if err = tx.Remove(&eventKeyed{Slug: "a"}); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+			keyed,
+			[]Event{{"store.eventKeyed", Inserted, []interface{}{"c"}}, {"store.eventKeyed", Deleted, []interface{}{"a"}}},
+		},
+		{
+			func() error { return s.Remove(&p) },
+			children,
+			[]Event{{"store.eventChild", Deleted, []interface{}{int64(1)}}},
+		},
+		{
+			func() error { return s.Remove(&eventKeyed{Slug: "z"}) },
+			keyed,
+			nil,
+		},
+		{
+			func() error { unsubscribe(); unsubscribe(); return s.Set(&eventParent{}) },
+			children,
+			nil,
+		},
+	} {
+		if err := test.fn(); err != nil && test.expected != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if events := receiveEvents(test.ch, len(test.expected)); !reflect.DeepEqual(events, test.expected) {
+			t.Errorf("test %d: expecting events %v, got %v", n+1, test.expected, events)
+		}
+	}
+	if _, err = s.Subscribe(new(testType), func(Event) {}); err != ErrUnregisteredType {
+		t.Errorf("test 11: expecting error %s, got %v", ErrUnregisteredType, err)
+	}
+}
+
+func TestSubscribeNonBlocking(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	var (
+		block    = make(chan struct{})
+		received = make(chan Event, 100)
+	)
+	if _, err = s.Subscribe(new(testType), func(e Event) {
+		<-block
+		received <- e
+	}); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	for n := 0; n < 50; n++ {
+		if err = s.Set(&testType{Number: int64(n)}); err != nil {
+			t.Fatalf("test 1: received unexpected error: %s", err)
+		}
+	}
+	close(block)
+	for n := 0; n < 50; n++ {
+		select {
+		case e := <-received:
+			if expected := []interface{}{int64(n + 1)}; !reflect.DeepEqual(e.Key, expected) {
+				t.Fatalf("test 2: expecting key %v, got %v", expected, e.Key)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("test 2: expecting 50 events, got %d", n)
+		}
+	}
+}
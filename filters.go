@@ -60,3 +60,237 @@ func (o Or) Vars() []interface{} {
 
 	return vars
 }
+
+func (a And) columns() []string {
+	return filterColumns(a)
+}
+
+func (o Or) columns() []string {
+	return filterColumns(o)
+}
+
+func (a And) varColumns() []string {
+	return filterVarColumns(a)
+}
+
+func (o Or) varColumns() []string {
+	return filterVarColumns(o)
+}
+
+type columnFilter interface {
+	columns() []string
+}
+
+// varColumnFilter is implemented by filters that know the column each of their
+// vars is compared with, so that the vars can be encoded as that column is.
+type varColumnFilter interface {
+	varColumns() []string
+}
+
+// filterVarColumns returns the columns compared with the vars of the given
+// filters, in order, with an empty string for any that are unknown.
+func filterVarColumns(fs []Filter) []string {
+	var cols []string
+
+	for _, f := range fs {
+		if vf, ok := f.(varColumnFilter); ok {
+			cols = append(cols, vf.varColumns()...)
+		} else {
+			cols = append(cols, make([]string, len(f.Vars()))...)
+		}
+	}
+
+	return cols
+}
+
+func filterColumns(fs []Filter) []string {
+	var cols []string
+
+	for _, f := range fs {
+		if cf, ok := f.(columnFilter); ok {
+			cols = append(cols, cf.columns()...)
+		}
+	}
+
+	return cols
+}
+
+type comparison struct {
+	column, op string
+	value      interface{}
+}
+
+// Eq matches records where the column is equal to the given value.
+//
+// The values of filters cannot be nil; to match NULL columns use IsNull.
+func Eq(column string, value interface{}) Filter {
+	return comparison{column, "=", value}
+}
+
+func NotEq(column string, value interface{}) Filter {
+	return comparison{column, "!=", value}
+}
+
+func Lt(column string, value interface{}) Filter {
+	return comparison{column, "<", value}
+}
+
+func Lte(column string, value interface{}) Filter {
+	return comparison{column, "<=", value}
+}
+
+func Gt(column string, value interface{}) Filter {
+	return comparison{column, ">", value}
+}
+
+func Gte(column string, value interface{}) Filter {
+	return comparison{column, ">=", value}
+}
+
+func Like(column string, pattern interface{}) Filter {
+	return comparison{column, "LIKE", pattern}
+}
+
+func (c comparison) SQL() string {
+	return "[" + c.column + "] " + c.op + " ?"
+}
+
+func (c comparison) Vars() []interface{} {
+	return []interface{}{c.value}
+}
+
+func (c comparison) columns() []string {
+	return []string{c.column}
+}
+
+func (c comparison) varColumns() []string {
+	return c.columns()
+}
+
+type between struct {
+	column    string
+	low, high interface{}
+}
+
+func Between(column string, low, high interface{}) Filter {
+	return between{column, low, high}
+}
+
+func (b between) SQL() string {
+	return "[" + b.column + "] BETWEEN ? AND ?"
+}
+
+func (b between) Vars() []interface{} {
+	return []interface{}{b.low, b.high}
+}
+
+func (b between) columns() []string {
+	return []string{b.column}
+}
+
+func (b between) varColumns() []string {
+	return []string{b.column, b.column}
+}
+
+type in struct {
+	column string
+	not    bool
+	values []interface{}
+}
+
+func In(column string, values ...interface{}) Filter {
+	return in{column, false, values}
+}
+
+func NotIn(column string, values ...interface{}) Filter {
+	return in{column, true, values}
+}
+
+func (i in) SQL() string {
+	sql := "[" + i.column + "] "
+
+	if i.not {
+		sql += "NOT "
+	}
+
+	sql += "IN ("
+
+	for n := range i.values {
+		if n > 0 {
+			sql += ", "
+		}
+
+		sql += "?"
+	}
+
+	sql += ")"
+
+	return sql
+}
+
+func (i in) Vars() []interface{} {
+	return i.values
+}
+
+func (i in) columns() []string {
+	return []string{i.column}
+}
+
+func (i in) varColumns() []string {
+	cols := make([]string, len(i.values))
+
+	for n := range cols {
+		cols[n] = i.column
+	}
+
+	return cols
+}
+
+type isNull struct {
+	column string
+	not    bool
+}
+
+func IsNull(column string) Filter {
+	return isNull{column, false}
+}
+
+func IsNotNull(column string) Filter {
+	return isNull{column, true}
+}
+
+func (i isNull) SQL() string {
+	if i.not {
+		return "[" + i.column + "] IS NOT NULL"
+	}
+
+	return "[" + i.column + "] IS NULL"
+}
+
+func (isNull) Vars() []interface{} {
+	return nil
+}
+
+func (i isNull) columns() []string {
+	return []string{i.column}
+}
+
+type not struct {
+	Filter
+}
+
+func Not(f Filter) Filter {
+	return not{f}
+}
+
+func (n not) SQL() string {
+	return "NOT (" + n.Filter.SQL() + ")"
+}
+
+func (n not) columns() []string {
+	return filterColumns([]Filter{n.Filter})
+}
+
+func (n not) varColumns() []string {
+	return filterVarColumns([]Filter{n.Filter})
+}
@@ -0,0 +1,80 @@
+package store
+
+import "context"
+
+// BeforeSetter is implemented by types that are to be validated or normalised
+// before being stored. Returning an error aborts the operation.
+type BeforeSetter interface {
+	BeforeSet(context.Context) error
+}
+
+// AfterSetter is implemented by types that are to be notified after being
+// stored, along with their nested structs and slices. Returning an error
+// aborts the operation.
+type AfterSetter interface {
+	AfterSet(context.Context) error
+}
+
+// AfterGetter is implemented by types that are to be notified after being
+// retrieved, along with their nested structs and slices. Returning an error
+// aborts the operation.
+type AfterGetter interface {
+	AfterGet(context.Context) error
+}
+
+// BeforeRemover is implemented by types that are to be notified before being
+// removed. Returning an error aborts the operation.
+type BeforeRemover interface {
+	BeforeRemove(context.Context) error
+}
+
+// AfterRemover is implemented by types that are to be notified after being
+// removed, along with the records removed by cascade delete policies, which
+// only have their keys set. Returning an error aborts the operation.
+type AfterRemover interface {
+	AfterRemove(context.Context) error
+}
+
+func beforeSet(ctx context.Context, i interface{}) error {
+	if h, ok := i.(BeforeSetter); ok {
+		return h.BeforeSet(ctx)
+	}
+
+	return nil
+}
+
+func afterSet(ctx context.Context, i interface{}) error {
+	if h, ok := i.(AfterSetter); ok {
+		return h.AfterSet(ctx)
+	}
+
+	return nil
+}
+
+func afterGet(ctx context.Context, is []interface{}) error {
+	for _, i := range is {
+		if h, ok := i.(AfterGetter); ok {
+			if err := h.AfterGet(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func beforeRemove(ctx context.Context, i interface{}) error {
+	if h, ok := i.(BeforeRemover); ok {
+		return h.BeforeRemove(ctx)
+	}
+
+	return nil
+}
+
+func afterRemove(ctx context.Context, i interface{}) error {
+	if h, ok := i.(AfterRemover); ok {
+		return h.AfterRemove(ctx)
+	}
+
+	return nil
+}
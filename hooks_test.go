@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errHook = errors.New("hook error")
+
+type hookLog []string
+
+func (h *hookLog) add(event, name string) {
+	if h != nil {
+		*h = append(*h, event+" "+name)
+	}
+}
+
+var hookEvents *hookLog
+
+type hookChild struct {
+	ID   int
+	Name string
+}
+
+func (h *hookChild) BeforeSet(context.Context) error {
+	hookEvents.add("BeforeSet", h.Name)
+
+	return nil
+}
+
+func (h *hookChild) AfterGet(context.Context) error {
+	hookEvents.add("AfterGet", h.Name)
+
+	return nil
+}
+
+func (h *hookChild) AfterRemove(context.Context) error {
+	hookEvents.add("AfterRemove", h.Name)
+
+	return nil
+}
+
+type hookParent struct {
+	ID    int
+	Name  string
+	Child hookChild `delete:"cascade"`
+}
+
+func (h *hookParent) BeforeSet(context.Context) error {
+	if h.Name == "" {
+		return errHook
+	}
+
+	h.Name = strings.TrimSpace(h.Name)
+
+	hookEvents.add("BeforeSet", h.Name)
+
+	return nil
+}
+
+func (h *hookParent) AfterSet(context.Context) error {
+	hookEvents.add("AfterSet", h.Name)
+
+	return nil
+}
+
+func (h *hookParent) AfterGet(ctx context.Context) error {
+	hookEvents.add("AfterGet", h.Name)
+
+	if h.Name == "fail" {
+		return errHook
+	}
+
+	return nil
+}
+
+func (h *hookParent) BeforeRemove(context.Context) error {
+	if h.Name == "keep" {
+		return errHook
+	}
+
+	hookEvents.add("BeforeRemove", h.Name)
+
+	return nil
+}
+
+func (h *hookParent) AfterRemove(context.Context) error {
+	hookEvents.add("AfterRemove", h.Name)
+
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(hookParent)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	var events hookLog
+	hookEvents = &events
+	defer func() { hookEvents = nil }()
+	for n, test := range []struct {
+		fn       func() error
+		err      error
+		expected []string
+	}{
+		{
+			func() error { return s.Set(&hookParent{Name: "  A  ", Child: hookChild{Name: "a"}}) },
+			nil,
+			[]string{"BeforeSet A", "BeforeSet a", "AfterSet A"},
+		},
+		{
+			func() error { return s.Set(&hookParent{Child: hookChild{Name: "b"}}) },
+			errHook,
+			[]string{},
+		},
+		{
+			func() error { return s.Get(&hookParent{ID: 1}) },
+			nil,
+			[]string{"AfterGet a", "AfterGet A"},
+		},
+		{
+			func() error {
+				_, err := s.GetPage([]interface{}{new(hookParent), new(hookParent)}, 0)
+				return err
+			},
+			nil,
+			[]string{"AfterGet a", "AfterGet A"},
+		},
+		{
+			func() error { return s.Remove(&hookParent{ID: 1, Name: "A", Child: hookChild{ID: 1, Name: "a"}}) },
+			nil,
+			[]string{"BeforeRemove A", "AfterRemove ", "AfterRemove A"},
+		},
+		{
+			func() error { return s.Set(&hookParent{Name: "fail", Child: hookChild{Name: "c"}}) },
+			nil,
+			[]string{"BeforeSet fail", "BeforeSet c", "AfterSet fail"},
+		},
+		{
+			func() error { return s.Get(&hookParent{ID: 2}) },
+			errHook,
+			[]string{"AfterGet c", "AfterGet fail"},
+		},
+		{
+			func() error { return s.Remove(&hookParent{ID: 2, Name: "keep"}) },
+			errHook,
+			[]string{},
+		},
+	} {
+		events = events[:0]
+		if err = test.fn(); err != test.err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.err, err)
+		} else if strings.Join(events, ", ") != strings.Join(test.expected, ", ") {
+			t.Errorf("test %d: expecting events %q, got %q", n+1, test.expected, events)
+		}
+	}
+	if c, err := s.Count(new(hookParent)); err != nil {
+		t.Errorf("test 9: received unexpected error: %s", err)
+	} else if c != 1 {
+		t.Errorf("test 9: expecting 1 record, got %d", c)
+	}
+}
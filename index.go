@@ -0,0 +1,133 @@
+package store
+
+import (
+	"strings"
+)
+
+type index struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+// UniqueError is returned when storing a record would duplicate the value of a
+// column with a unique index.
+type UniqueError struct {
+	Table, Column string
+}
+
+func (u UniqueError) Error() string {
+	return "unique constraint violated: " + u.Table + "." + u.Column
+}
+
+func (UniqueError) Unwrap() error {
+	return ErrUniqueViolation
+}
+
+// typeIndexes collects the indexes of a table from its fields, with named
+// indexes spanning every field that shares the name, in field order.
+func typeIndexes(table string, fields []field) ([]index, error) {
+	var (
+		indexes []index
+		named   = make(map[string]int)
+	)
+
+	for _, f := range fields {
+		if f.unique {
+			indexes = append(indexes, index{
+				name:    table + "." + f.name + "_key",
+				unique:  true,
+				columns: []string{f.name},
+			})
+		}
+
+		for _, name := range f.indexes {
+			if name == "" {
+				return nil, ErrInvalidTag
+			}
+
+			n, ok := named[name]
+			if !ok {
+				n = len(indexes)
+				named[name] = n
+
+				indexes = append(indexes, index{name: table + "." + name + "_idx"})
+			}
+
+			indexes[n].columns = append(indexes[n].columns, f.name)
+		}
+	}
+
+	return indexes, nil
+}
+
+func indexNames(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+
+	return strings.Split(tag, ",")
+}
+
+func (i index) createSQL(table string) string {
+	sql := "CREATE "
+
+	if i.unique {
+		sql += "UNIQUE "
+	}
+
+	return sql + "INDEX [" + i.name + "] ON [" + table + "] ([" + strings.Join(i.columns, "], [") + "]);"
+}
+
+// indexSQL returns the statements that create the indexes of a table that do
+// not already exist.
+func (s *Store) indexSQL(name string, t *typeInfo, rebuilt bool) ([]string, error) {
+	if len(t.indexes) == 0 {
+		return nil, nil
+	}
+
+	existing := make(map[string]struct{})
+
+	if !rebuilt {
+		indexes, err := s.dialect.Indexes(s.db, name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range indexes {
+			existing[i] = struct{}{}
+		}
+	}
+
+	var ddl []string
+
+	for _, i := range t.indexes {
+		if _, ok := existing[i.name]; !ok {
+			ddl = append(ddl, rebind(s.dialect, i.createSQL(name)))
+		}
+	}
+
+	return ddl, nil
+}
+
+// uniqueError converts an error caused by a unique index on the given table
+// into a UniqueError. As the key is checked before inserting, any other
+// violation is reported as ErrExists.
+func (s *Store) uniqueError(name string, t *typeInfo, err error) error {
+	constraint, ok := s.dialect.UniqueViolation(err)
+	if !ok {
+		return err
+	}
+
+	for _, i := range t.indexes {
+		if !i.unique {
+			continue
+		}
+
+		if constraint == i.name || strings.HasSuffix(constraint, "."+i.name) || constraint == name+"."+i.columns[0] {
+			return UniqueError{Table: name, Column: i.columns[0]}
+		}
+	}
+
+	return ErrExists
+}
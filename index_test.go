@@ -0,0 +1,168 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type indexedUser struct {
+	ID      int
+	Email   string `store:"email,unique"`
+	First   string `index:"name"`
+	Last    string `index:"name,last"`
+	Company string
+}
+
+type badIndexTag struct {
+	ID   int
+	Name string `index:"a,"`
+}
+
+type badIndexSlice struct {
+	ID   int
+	Tags []string `store:"tags,unique"`
+}
+
+func TestIndexes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	ddl, err := s.PlanMigration(new(indexedUser))
+	if err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	expected := []string{
+		"CREATE TABLE IF NOT EXISTS [store.indexedUser]([ID] INTEGER PRIMARY KEY AUTOINCREMENT, [email] TEXT, [First] TEXT, [Last] TEXT, [Company] TEXT);",
+		"CREATE UNIQUE INDEX [store.indexedUser.email_key] ON [store.indexedUser] ([email]);",
+		"CREATE INDEX [store.indexedUser.name_idx] ON [store.indexedUser] ([First], [Last]);",
+		"CREATE INDEX [store.indexedUser.last_idx] ON [store.indexedUser] ([Last]);",
+	}
+	if !reflect.DeepEqual(ddl, expected) {
+		t.Fatalf("test 1: expecting DDL %q, got %q", expected, ddl)
+	}
+	if err = s.Register(new(indexedUser)); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	if indexes, err := s.dialect.Indexes(s.db, "store.indexedUser"); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if len(indexes) != 3 {
+		t.Errorf("test 2: expecting 3 indexes, got %q", indexes)
+	}
+	s.Close()
+	if s, err = New(path); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if ddl, err = s.PlanMigration(new(indexedUser)); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if len(ddl) != 0 {
+		t.Errorf("test 3: expecting no DDL, got %q", ddl)
+	}
+	s.Close()
+
+	{
+		type indexedUser struct {
+			ID      int
+			Mail    string `store:"mail,unique" was:"email"`
+			First   string `index:"name"`
+			Last    string `index:"name"`
+			Company string `index:"company"`
+		}
+		if s, err = New(path); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		defer s.Close()
+		if ddl, err = s.PlanMigration(new(indexedUser)); err != nil {
+			t.Fatalf("test 4: received unexpected error: %s", err)
+		} else if len(ddl) != 7 {
+			t.Fatalf("test 4: expecting 7 DDL statements, got %q", ddl)
+		} else if expected := []string{
+			"CREATE UNIQUE INDEX [store.indexedUser.mail_key] ON [store.indexedUser] ([mail]);",
+			"CREATE INDEX [store.indexedUser.name_idx] ON [store.indexedUser] ([First], [Last]);",
+			"CREATE INDEX [store.indexedUser.company_idx] ON [store.indexedUser] ([Company]);",
+		}; !reflect.DeepEqual(ddl[4:], expected) {
+			t.Errorf("test 4: expecting index DDL %q, got %q", expected, ddl[4:])
+		}
+		if err = s.Register(new(indexedUser)); err != nil {
+			t.Fatalf("test 5: received unexpected error: %s", err)
+		}
+		if err = s.Set(&indexedUser{Mail: "a"}); err != nil {
+			t.Errorf("test 5: received unexpected error: %s", err)
+		} else if err = s.Set(&indexedUser{Mail: "a"}); !errors.Is(err, ErrUniqueViolation) {
+			t.Errorf("test 5: expecting error %s, got %v", ErrUniqueViolation, err)
+		}
+	}
+}
+
+func TestUniqueViolation(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(indexedUser)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	a := indexedUser{Email: "a@example.com", First: "A"}
+	b := indexedUser{Email: "b@example.com", First: "B"}
+	if err = s.Set(&a, &b); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	expected := UniqueError{Table: "store.indexedUser", Column: "email"}
+	for n, test := range []func() error{
+		func() error { return s.Set(&indexedUser{Email: "a@example.com"}) },
+		func() error { return s.Insert(&indexedUser{ID: 10, Email: "a@example.com"}) },
+		func() error { return s.Update(&indexedUser{ID: b.ID, Email: "a@example.com"}) },
+		func() error { return s.Set(&indexedUser{ID: b.ID, Email: "a@example.com"}) },
+	} {
+		if err := test(); err != expected {
+			t.Errorf("test %d: expecting error %v, got %v", n+2, expected, err)
+		} else if !errors.Is(err, ErrUniqueViolation) {
+			t.Errorf("test %d: expecting error to wrap %s", n+2, ErrUniqueViolation)
+		}
+	}
+	if n, err := s.Count(new(indexedUser)); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if n != 2 {
+		t.Errorf("test 6: expecting count 2, got %d", n)
+	}
+	if err = s.Set(&indexedUser{Email: "c@example.com", First: "A"}); err != nil {
+		t.Errorf("test 7: received unexpected error: %s", err)
+	}
+}
+
+func TestIndexErrors(t *testing.T) {
+	for n, i := range []interface{}{new(badIndexTag), new(badIndexSlice)} {
+		s, err := newTestStore()
+		if err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		if err = s.Register(i); err != ErrInvalidTag {
+			t.Errorf("test %d: expecting error %s, got %v", n+1, ErrInvalidTag, err)
+		}
+		s.Close()
+	}
+}
+
+func TestConstraintName(t *testing.T) {
+	for n, test := range []struct {
+		dialect    Dialect
+		err        error
+		constraint string
+		ok         bool
+	}{
+		{SQLite, errors.New("sqlite3: UNIQUE constraint failed: a.b.E [2067]"), "a.b.E", true},
+		{SQLite, errors.New("sqlite3: UNIQUE constraint failed: a.b.F, a.b.G [2067]"), "a.b.F, a.b.G", true},
+		{SQLite, errors.New("sqlite3: NOT NULL constraint failed: a.b.E [1299]"), "", false},
+		{PostgreSQL, errors.New("pq: duplicate key value violates unique constraint \"a.b.E_key\""), "a.b.E_key", true},
+		{MySQL, errors.New("Error 1062 (23000): Duplicate entry 'x' for key 'a.b.a.b.E_key'"), "a.b.a.b.E_key", true},
+		{MySQL, nil, "", false},
+	} {
+		if constraint, ok := test.dialect.UniqueViolation(test.err); constraint != test.constraint || ok != test.ok {
+			t.Errorf("test %d: expecting %q, %v, got %q, %v", n+1, test.constraint, test.ok, constraint, ok)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package store
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// keyFields determines the fields, in order, that make up the key of a struct
+// type.
+//
+// Fields tagged with `key:"1"`, `key:"2"`, etc. form the key, in the order of
+// their tag values, and may be integers, strings or byte arrays. Otherwise, the
+// key is the integer field named ID, or the first integer field.
+//
+// Foreign keys and child tables only use a single column, so types with a
+// composite key cannot be referenced by nested structs or slices, and cannot
+// have slice fields themselves, with either returning ErrCompositeKey.
+func keyFields(t reflect.Type) ([]int, error) {
+	var (
+		tagged     = make(map[int]int)
+		best, rank = -1, 0
+	)
+
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, _ := parseTag(f)
+		if name == "-" {
+			continue
+		}
+
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if tag := f.Tag.Get("key"); tag != "" {
+			order, err := strconv.Atoi(tag)
+			if err != nil || order < 1 || !isKeyType(ft) {
+				return nil, ErrInvalidTag
+			} else if _, ok := tagged[order]; ok {
+				return nil, ErrInvalidTag
+			}
+
+			tagged[order] = n
+
+			continue
+		}
+
+		if !isIntKeyType(ft) {
+			continue
+		}
+
+		r := 1
+		if strings.ToLower(name) == "id" {
+			r = 2
+		}
+
+		if r > rank {
+			best, rank = n, r
+		}
+	}
+
+	if len(tagged) > 0 {
+		keys := make([]int, len(tagged))
+
+		for order, n := range tagged {
+			if order > len(keys) {
+				return nil, ErrInvalidTag
+			}
+
+			keys[order-1] = n
+		}
+
+		return keys, nil
+	} else if best == -1 {
+		return nil, ErrNoKey
+	}
+
+	return []int{best}, nil
+}
+
+func isIntKeyType(t reflect.Type) bool {
+	if t == timeType {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+func isKeyType(t reflect.Type) bool {
+	if isIntKeyType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Array:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+// referencedKey returns the name and column type of the key of a struct type
+// that is to be referenced by a foreign key.
+func referencedKey(t reflect.Type) (string, string, error) {
+	keys, err := keyFields(t)
+	if err != nil {
+		return "", "", err
+	} else if len(keys) > 1 {
+		return "", "", ErrCompositeKey
+	}
+
+	name, _ := parseTag(t.Field(keys[0]))
+
+	return name, getType(reflect.New(t).Interface(), keys[0]), nil
+}
+
+func (t *typeInfo) isKey(pos int) bool {
+	for _, k := range t.keys {
+		if k == pos {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *typeInfo) keyField(n int) field {
+	return t.fields[t.keys[n]]
+}
+
+// GetID returns the values of the key columns of the given record.
+func (t *typeInfo) GetID(i interface{}) []interface{} {
+	if !isPointerStruct(i) {
+		return nil
+	}
+
+	key := make([]interface{}, len(t.keys))
+
+	for n := range t.keys {
+		f := t.keyField(n)
+
+		v := reflect.ValueOf(i).Elem().Field(f.pos)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v = reflect.New(v.Type().Elem())
+			}
+
+			v = v.Elem()
+		}
+
+		key[n] = keyValue(v, f.encoding)
+	}
+
+	return key
+}
+
+func keyValue(v reflect.Value, enc uint8) interface{} {
+	switch enc {
+	case encodeValuer:
+		value, err := v.Addr().Interface().(driver.Valuer).Value()
+		if err != nil {
+			return nil
+		}
+
+		return value
+	case encodeText:
+		text, err := v.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil
+		}
+
+		return string(text)
+	case encodeBytes:
+		b, _ := encodeValue(v.Addr().Interface(), enc)
+
+		return b
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint())
+	case reflect.String:
+		return v.String()
+	}
+
+	return nil
+}
+
+// SetID sets the key columns of the given record to the given values.
+func (t *typeInfo) SetID(i interface{}, key []interface{}) error {
+	if !isPointerStruct(i) || len(key) != len(t.keys) {
+		return ErrInvalidType
+	}
+
+	for n, s := range t.keyScanners(i) {
+		if err := s.(sql.Scanner).Scan(key[n]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasID returns true if any of the key columns of the given record are set.
+func (t *typeInfo) hasID(i interface{}) bool {
+	for _, k := range t.keys {
+		if !reflect.ValueOf(i).Elem().Field(t.fields[k].pos).IsZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *typeInfo) clearID(i interface{}) {
+	for _, k := range t.keys {
+		v := reflect.ValueOf(i).Elem().Field(t.fields[k].pos)
+
+		v.Set(reflect.Zero(v.Type()))
+	}
+}
+
+func (t *typeInfo) keyScanners(i interface{}) []interface{} {
+	scanners := make([]interface{}, len(t.keys))
+
+	for n := range t.keys {
+		f := t.keyField(n)
+		scanners[n] = keyScanner{reflect.ValueOf(i).Elem().Field(f.pos), f.encoding}
+	}
+
+	return scanners
+}
+
+type keyScanner struct {
+	value    reflect.Value
+	encoding uint8
+}
+
+func (k keyScanner) Scan(src interface{}) error {
+	v := k.value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+
+	switch k.encoding {
+	case encodeValuer:
+		return v.Addr().Interface().(sql.Scanner).Scan(src)
+	case encodeText, encodeBytes:
+		return scanner(v.Addr().Interface(), k.encoding).(sql.Scanner).Scan(src)
+	}
+
+	if src == nil {
+		v.Set(reflect.Zero(v.Type()))
+
+		return nil
+	}
+
+	s := reflect.ValueOf(src)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s.CanInt() {
+			v.SetInt(s.Int())
+
+			return nil
+		} else if s.CanUint() {
+			v.SetInt(int64(s.Uint()))
+
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s.CanInt() {
+			v.SetUint(uint64(s.Int()))
+
+			return nil
+		} else if s.CanUint() {
+			v.SetUint(s.Uint())
+
+			return nil
+		}
+	case reflect.String:
+		switch src := src.(type) {
+		case string:
+			v.SetString(src)
+
+			return nil
+		case []byte:
+			v.SetString(string(src))
+
+			return nil
+		}
+	}
+
+	return ErrInvalidType
+}
+
+// mapKey converts the values of a key to a value that can be used as a map key.
+func mapKey(key []interface{}) interface{} {
+	if len(key) == 1 {
+		switch k := key[0].(type) {
+		case int64, string:
+			return k
+		}
+	}
+
+	return fmt.Sprintf("%#v", key)
+}
+
+func (t *typeInfo) keyWhere() string {
+	var where string
+
+	for n := range t.keys {
+		if n > 0 {
+			where += " AND "
+		}
+
+		where += "[" + t.keyField(n).name + "] = ?"
+	}
+
+	return where
+}
+
+// keyIn returns a condition matching any of num keys.
+func (t *typeInfo) keyIn(num int) string {
+	if len(t.keys) == 1 {
+		return "[" + t.keyField(0).name + "] IN (" + placeholders(num) + ")"
+	}
+
+	where := "(" + t.keyWhere() + ")"
+
+	return "(" + where + strings.Repeat(" OR "+where, num-1) + ")"
+}
+
+func flattenKeys(keys [][]interface{}) []interface{} {
+	var args []interface{}
+
+	for _, key := range keys {
+		args = append(args, key...)
+	}
+
+	return args
+}
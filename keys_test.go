@@ -0,0 +1,274 @@
+package store
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+type slugPage struct {
+	Slug  string `key:"1"`
+	Title string
+}
+
+type uuid [16]byte
+
+func (u uuid) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(u[:])), nil
+}
+
+func (u *uuid) UnmarshalText(text []byte) error {
+	_, err := hex.Decode(u[:], text)
+
+	return err
+}
+
+type rawKeyed struct {
+	Key  [16]byte `key:"1"`
+	Data string
+}
+
+type textKeyed struct {
+	Key  uuid `key:"1"`
+	Data string
+}
+
+type compositeKeyed struct {
+	Data   string
+	Region string `key:"1"`
+	Number int    `key:"2"`
+}
+
+type slugRef struct {
+	ID    int
+	Page  *slugPage `delete:"cascade"`
+	Pages []slugPage
+}
+
+type compositeRef struct {
+	ID  int
+	Ref *compositeKeyed
+}
+
+type compositeSlice struct {
+	Region string `key:"1"`
+	Number int    `key:"2"`
+	Tags   []string
+}
+
+type badKeyTag struct {
+	A int `key:"1"`
+	B int `key:"3"`
+}
+
+type badKeyType struct {
+	A float64 `key:"1"`
+}
+
+func TestStringKey(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	c, err := NewCollection[slugPage](s)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = c.Set(&slugPage{"home", "Home"}, &slugPage{"about", "About"}); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if p, err := c.Get("about"); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if p.Title != "About" {
+		t.Errorf("test 2: expecting title %q, got %q", "About", p.Title)
+	}
+	if err = c.Set(&slugPage{"about", "About Us"}); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if p, err := c.Get("about"); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if p.Title != "About Us" {
+		t.Errorf("test 3: expecting title %q, got %q", "About Us", p.Title)
+	}
+	if err = c.Set(&slugPage{Title: "None"}); err != ErrNoKey {
+		t.Errorf("test 4: expecting error %s, got %v", ErrNoKey, err)
+	}
+	if err = s.Insert(&slugPage{"home", "Again"}); err != ErrExists {
+		t.Errorf("test 5: expecting error %s, got %v", ErrExists, err)
+	}
+	if err = c.Remove(&slugPage{Slug: "home"}); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if _, err = c.Get("home"); err != ErrNotFound {
+		t.Errorf("test 6: expecting error %s, got %v", ErrNotFound, err)
+	}
+}
+
+func TestByteKeys(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(rawKeyed), new(textKeyed)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	raw := rawKeyed{[16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, "raw"}
+	text := textKeyed{uuid{0xde, 0xad, 0xbe, 0xef}, "text"}
+	if err = s.Set(&raw, &text); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	gotRaw := rawKeyed{Key: raw.Key}
+	gotText := textKeyed{Key: text.Key}
+	if err = s.Get(&gotRaw, &gotText); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if gotRaw != raw {
+		t.Errorf("test 2: expecting %v, got %v", raw, gotRaw)
+	} else if gotText != text {
+		t.Errorf("test 2: expecting %v, got %v", text, gotText)
+	}
+	var stored string
+	if err = s.db.QueryRow("SELECT [Key] FROM [store.textKeyed];").Scan(&stored); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if expected := "deadbeef000000000000000000000000"; stored != expected {
+		t.Errorf("test 3: expecting stored key %q, got %q", expected, stored)
+	}
+	missing := rawKeyed{Key: [16]byte{1}}
+	if err = s.Get(&missing); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if missing.Key != [16]byte{} {
+		t.Errorf("test 4: expecting key to be cleared, got %v", missing.Key)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	c, err := NewCollection[compositeKeyed](s)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	values := []*compositeKeyed{
+		{"A1", "a", 1},
+		{"A2", "a", 2},
+		{"B1", "b", 1},
+	}
+	if err = c.Insert(values...); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if v, err := c.Get("b", 1); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if *v != *values[2] {
+		t.Errorf("test 2: expecting %v, got %v", values[2], v)
+	}
+	if _, err = c.Get("b", 2); err != ErrNotFound {
+		t.Errorf("test 3: expecting error %s, got %v", ErrNotFound, err)
+	}
+	if _, err = c.Get("b"); err != ErrInvalidType {
+		t.Errorf("test 4: expecting error %s, got %v", ErrInvalidType, err)
+	}
+	if err = c.Update(&compositeKeyed{"A2!", "a", 2}); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if v, err := c.Get("a", 2); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if v.Data != "A2!" {
+		t.Errorf("test 5: expecting data %q, got %q", "A2!", v.Data)
+	}
+	if err = c.Update(&compositeKeyed{"C1", "c", 1}); err != ErrNotFound {
+		t.Errorf("test 6: expecting error %s, got %v", ErrNotFound, err)
+	}
+	page := []interface{}{new(compositeKeyed), new(compositeKeyed), new(compositeKeyed)}
+	if n, err := s.GetPage(page, 0); err != nil {
+		t.Errorf("test 7: received unexpected error: %s", err)
+	} else if n != 3 {
+		t.Errorf("test 7: expecting 3 records, got %d", n)
+	}
+	if tts, err := c.Search(Eq("Number", 1), 0, 5, SortBy{"Region", false}); err != nil {
+		t.Errorf("test 8: received unexpected error: %s", err)
+	} else if expected := []compositeKeyed{*values[2], *values[0]}; !reflect.DeepEqual(tts, expected) {
+		t.Errorf("test 8: expecting %v, got %v", expected, tts)
+	}
+	p, err := s.NewSearch(new(compositeKeyed)).Prepare()
+	if err != nil {
+		t.Fatalf("test 9: received unexpected error: %s", err)
+	}
+	cur, err := p.Cursor()
+	if err != nil {
+		t.Fatalf("test 9: received unexpected error: %s", err)
+	}
+	var keys []string
+	for cur.Next() {
+		var v compositeKeyed
+		if err = cur.Scan(&v); err != nil {
+			t.Errorf("test 9: received unexpected error: %s", err)
+		}
+		keys = append(keys, v.Data)
+	}
+	cur.Close()
+	if err = cur.Err(); err != nil {
+		t.Errorf("test 9: received unexpected error: %s", err)
+	} else if expected := []string{"A1", "A2!", "B1"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("test 9: expecting %v, got %v", expected, keys)
+	}
+	if err = c.Remove(&compositeKeyed{Region: "a", Number: 1}); err != nil {
+		t.Errorf("test 10: received unexpected error: %s", err)
+	} else if n, err := c.Count(); err != nil {
+		t.Errorf("test 10: received unexpected error: %s", err)
+	} else if n != 2 {
+		t.Errorf("test 10: expecting count 2, got %d", n)
+	}
+}
+
+func TestKeyReferences(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(slugRef)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	ref := slugRef{
+		Page:  &slugPage{"home", "Home"},
+		Pages: []slugPage{{"a", "A"}, {"b", "B"}},
+	}
+	if err = s.Set(&ref); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	got := slugRef{ID: ref.ID}
+	if err = s.Get(&got); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(got, ref) {
+		t.Errorf("test 2: expecting %v, got %v", ref, got)
+	}
+	if err = s.Remove(&ref); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if n, err := s.Count(new(slugPage)); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if n != 2 {
+		t.Errorf("test 3: expecting count 2, got %d", n)
+	}
+}
+
+func TestKeyErrors(t *testing.T) {
+	for n, test := range []struct {
+		i   interface{}
+		err error
+	}{
+		{new(compositeRef), ErrCompositeKey},
+		{new(compositeSlice), ErrCompositeKey},
+		{new(badKeyTag), ErrInvalidTag},
+		{new(badKeyType), ErrInvalidTag},
+	} {
+		s, err := newTestStore()
+		if err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		if err = s.Register(test.i); err != test.err {
+			t.Errorf("test %d: expecting error %s, got %v", n+1, test.err, err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// LoadOption configures how nested structs are retrieved.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	depth       int
+	withDeleted bool
+}
+
+// Depth limits how many levels of nested structs are retrieved. A depth of zero
+// retrieves only the record itself, setting just the keys of any nested structs.
+func Depth(depth int) LoadOption {
+	return func(o *loadOptions) {
+		o.depth = depth
+	}
+}
+
+// WithDeleted includes soft deleted records when retrieving a record. Nested
+// structs are always retrieved, whether or not they have been soft deleted.
+func WithDeleted() LoadOption {
+	return func(o *loadOptions) {
+		o.withDeleted = true
+	}
+}
+
+func getOptions(opts []LoadOption) loadOptions {
+	o := loadOptions{depth: -1}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// related returns the options for retrieving the nested structs of records.
+func (o loadOptions) related() loadOptions {
+	return loadOptions{depth: o.depth - 1, withDeleted: true}
+}
+
+func (s *Store) GetWith(i interface{}, opts ...LoadOption) error {
+	return s.GetWithContext(context.Background(), i, opts...)
+}
+
+func (s *Store) GetWithContext(ctx context.Context, i interface{}, opts ...LoadOption) error {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	return s.get(ctx, nil, getOptions(opts), i)
+}
+
+// Load retrieves the nested struct, or slice of structs, stored in the named
+// field of an already retrieved record, such as those marked as lazy.
+func (s *Store) Load(i interface{}, field string, opts ...LoadOption) error {
+	return s.LoadContext(context.Background(), i, field, opts...)
+}
+
+func (s *Store) LoadContext(ctx context.Context, i interface{}, field string, opts ...LoadOption) error {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	return s.load(ctx, nil, i, field, getOptions(opts))
+}
+
+func (s *Store) load(ctx context.Context, tx *sql.Tx, i interface{}, field string, opts loadOptions) error {
+	opts.withDeleted = true
+
+	t, ok := s.types[typeName(i)]
+	if !ok {
+		return ErrUnregisteredType
+	}
+
+	for _, f := range t.fields {
+		if !f.isStruct || !t.isField(f.pos, f.name, field) {
+			continue
+		}
+
+		ni := getFieldPointer(i, f.pos)
+		if reflect.ValueOf(ni).IsNil() {
+			return nil
+		}
+
+		return s.get(ctx, tx, opts, ni)
+	}
+
+	for _, c := range t.children {
+		if !c.isStruct || !t.isField(c.pos, c.name, field) {
+			continue
+		}
+
+		v := reflect.ValueOf(i).Elem().Field(c.pos)
+		elems := make([]interface{}, 0, v.Len())
+
+		for n := 0; n < v.Len(); n++ {
+			e := v.Index(n)
+
+			if !c.isPointer {
+				e = e.Addr()
+			} else if e.IsNil() {
+				continue
+			}
+
+			elems = append(elems, e.Interface())
+		}
+
+		return s.get(ctx, tx, opts, elems...)
+	}
+
+	return ErrUnknownColumn
+}
+
+func (t *typeInfo) isField(pos int, name, field string) bool {
+	return strings.EqualFold(name, field) || t.typ.Field(pos).Name == field
+}
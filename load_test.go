@@ -0,0 +1,68 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+type lazyType struct {
+	ID     int
+	Name   string
+	Nested *embeddedTestType `store:",lazy"`
+	Items  []lineItem        `store:",lazy"`
+	Eager  embeddedTestType
+}
+
+func TestLoad(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(lazyType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	lt := lazyType{
+		Name:   "Lazy",
+		Nested: &embeddedTestType{0, "Beep", testType{0, "Boop", 1}},
+		Items:  []lineItem{{Name: "A", Quantity: 1}, {Name: "B", Quantity: 2}},
+		Eager:  embeddedTestType{0, "Hello", testType{0, "World", 2}},
+	}
+	if err = s.Set(&lt); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	got := lazyType{ID: lt.ID}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if expected := (embeddedTestType{ID: lt.Nested.ID}); got.Nested == nil || *got.Nested != expected {
+		t.Errorf("test 2: expecting only key for lazy field, got %v", got.Nested)
+	} else if expected := []lineItem{{ID: lt.Items[0].ID}, {ID: lt.Items[1].ID}}; !reflect.DeepEqual(got.Items, expected) {
+		t.Errorf("test 2: expecting only keys for lazy slice, got %v", got.Items)
+	} else if got.Eager != lt.Eager {
+		t.Errorf("test 2: expecting eager field %v, got %v", lt.Eager, got.Eager)
+	}
+	if err = s.Load(&got, "Nested", Depth(0)); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	} else if expected := (embeddedTestType{lt.Nested.ID, "Beep", testType{ID: lt.Nested.AnotherType.ID}}); *got.Nested != expected {
+		t.Errorf("test 3: expecting %v, got %v", expected, got.Nested)
+	}
+	if err = s.Load(&got, "Nested"); err != nil {
+		t.Fatalf("test 4: received unexpected error: %s", err)
+	} else if *got.Nested != *lt.Nested {
+		t.Errorf("test 4: expecting %v, got %v", lt.Nested, got.Nested)
+	}
+	if err = s.Load(&got, "Items"); err != nil {
+		t.Fatalf("test 5: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(got.Items, lt.Items) {
+		t.Errorf("test 5: expecting %v, got %v", lt.Items, got.Items)
+	}
+	if err = s.Load(&got, "Missing"); err != ErrUnknownColumn {
+		t.Errorf("test 6: expecting error %s, got %v", ErrUnknownColumn, err)
+	}
+	got = lazyType{ID: lt.ID}
+	if err = s.GetWith(&got, Depth(1)); err != nil {
+		t.Fatalf("test 7: received unexpected error: %s", err)
+	} else if expected := (embeddedTestType{lt.Eager.ID, "Hello", testType{ID: lt.Eager.AnotherType.ID}}); got.Eager != expected {
+		t.Errorf("test 7: expecting %v, got %v", expected, got.Eager)
+	}
+}
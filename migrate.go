@@ -0,0 +1,270 @@
+package store
+
+import "strings"
+
+// ColumnError is returned when an existing table column cannot be migrated to
+// match the registered type.
+type ColumnError struct {
+	Table, Column, Type, Expected string
+}
+
+func (c ColumnError) Error() string {
+	if c.Type == "" {
+		return "incompatible column: " + c.Table + "." + c.Column + " is missing"
+	}
+
+	return "incompatible column: " + c.Table + "." + c.Column + " has type " + c.Type + ", expected " + c.Expected
+}
+
+func (ColumnError) Unwrap() error {
+	return ErrIncompatibleColumn
+}
+
+// PlanMigration returns the DDL statements that Register would run to create
+// or migrate the tables for the given types, without running them.
+func (s *Store) PlanMigration(is ...interface{}) ([]string, error) {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	if s.db == nil {
+		return nil, ErrDBClosed
+	}
+
+	var (
+		ddl  []string
+		seen = make(map[string]struct{})
+		plan func(interface{}) error
+	)
+
+	plan = func(i interface{}) error {
+		name := typeName(i)
+		if _, ok := s.types[name]; ok {
+			return nil
+		} else if _, ok := seen[name]; ok {
+			return nil
+		}
+
+		seen[name] = struct{}{}
+
+		t, err := s.typeFields(i, plan)
+		if err != nil {
+			return err
+		}
+
+		tddl, err := s.schemaSQL(name, i, &t)
+		if err != nil {
+			return err
+		}
+
+		ddl = append(ddl, tddl...)
+
+		return nil
+	}
+
+	for _, i := range is {
+		if !isPointerStruct(i) {
+			return nil, ErrNoPointerStruct
+		}
+
+		if err := plan(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return ddl, nil
+}
+
+func (s *Store) schemaSQL(name string, i interface{}, t *typeInfo) ([]string, error) {
+	ddl, rebuilt, err := s.tableSQL(name, i, t)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := s.indexSQL(name, t, rebuilt)
+	if err != nil {
+		return nil, err
+	}
+
+	ddl = append(ddl, indexes...)
+
+	for _, c := range t.children {
+		columns, err := s.dialect.Columns(s.db, c.table)
+		if err != nil {
+			return nil, err
+		} else if len(columns) == 0 {
+			create, _ := buildChildSQL(s.dialect, c.table, c.parentType, c.sqlType)
+			ddl = append(ddl, create)
+		}
+	}
+
+	return ddl, nil
+}
+
+// tableSQL returns the statements that create or migrate the table for a type,
+// and whether the table is to be rebuilt, losing its indexes.
+func (s *Store) tableSQL(name string, i interface{}, t *typeInfo) ([]string, bool, error) {
+	fields := t.fields
+	create, _ := buildSQL(s.dialect, name, i, fields, t.keys, t.auto)
+
+	columns, err := s.dialect.Columns(s.db, name)
+	if err != nil {
+		return nil, false, err
+	} else if len(columns) == 0 {
+		return []string{create}, false, nil
+	}
+
+	existing := make(map[string]Column, len(columns))
+
+	for _, c := range columns {
+		existing[c.Name] = c
+	}
+
+	var (
+		ddl, renames []string
+		sources      = make([]string, len(fields))
+		rebuild      bool
+	)
+
+	for pos, f := range fields {
+		c, ok := existing[f.name]
+		if !ok && f.was != "" {
+			if c, ok = existing[f.was]; ok {
+				rename := s.dialect.RenameColumn(name, f.was, f.name)
+				if rename == "" {
+					rebuild = true
+				} else {
+					renames = append(renames, rebind(s.dialect, rename))
+				}
+			}
+		}
+
+		if !ok {
+			if t.isKey(pos) {
+				return nil, false, ColumnError{Table: name, Column: f.name}
+			}
+
+			typ := fieldType(i, f)
+			column := s.dialect.ColumnType(columnType(i, f, false))
+			if f.def == "" && s.dialect.LiteralDefault(column) {
+				column += " DEFAULT " + f.defaultValue(typ)
+			}
+
+			ddl = append(ddl, rebind(s.dialect, "ALTER TABLE ["+name+"] ADD COLUMN ["+f.name+"] "+column+f.constraints()+";"))
+
+			continue
+		}
+
+		delete(existing, c.Name)
+
+		sources[pos] = c.Name
+
+		if t.isKey(pos) || c.Type == "" {
+			continue
+		}
+
+		if expected := s.dialect.ColumnType(columnType(i, f, false)); !sameColumnType(c.Type, expected) {
+			return nil, false, ColumnError{
+				Table:    name,
+				Column:   f.name,
+				Type:     c.Type,
+				Expected: expected,
+			}
+		}
+	}
+
+	if !rebuild {
+		return append(renames, ddl...), false, nil
+	}
+
+	tmp := name + "_migrate"
+	tmpCreate, _ := buildSQL(s.dialect, tmp, i, fields, t.keys, t.auto)
+	ddl = []string{tmpCreate}
+
+	var cols, srcs string
+
+	for pos, f := range fields {
+		if cols != "" {
+			cols += ", "
+			srcs += ", "
+		}
+
+		cols += "[" + f.name + "]"
+
+		if sources[pos] == "" {
+			srcs += f.defaultValue(fieldType(i, f))
+		} else {
+			srcs += "[" + sources[pos] + "]"
+		}
+	}
+
+	for _, c := range columns {
+		if _, ok := existing[c.Name]; ok {
+			ddl = append(ddl, rebind(s.dialect, "ALTER TABLE ["+tmp+"] ADD COLUMN ["+c.Name+"] "+c.Type+";"))
+			cols += ", [" + c.Name + "]"
+			srcs += ", [" + c.Name + "]"
+		}
+	}
+
+	return append(ddl,
+		rebind(s.dialect, "INSERT INTO ["+tmp+"] ("+cols+") SELECT "+srcs+" FROM ["+name+"];"),
+		rebind(s.dialect, "DROP TABLE ["+name+"];"),
+		rebind(s.dialect, "ALTER TABLE ["+tmp+"] RENAME TO ["+name+"];"),
+	), true, nil
+}
+
+// defaultValue returns the value given to the column of a field when it is
+// added to an existing table.
+func (f field) defaultValue(sqlType string) string {
+	if f.def != "" {
+		return f.def
+	} else if f.nullable && !f.notNull {
+		return "NULL"
+	}
+
+	return zeroValue(sqlType)
+}
+
+func zeroValue(sqlType string) string {
+	switch sqlType {
+	case "INTEGER", "FLOAT":
+		return "0"
+	case "TEXT", "BLOB":
+		return "''"
+	case "BOOLEAN":
+		return "'0'"
+	}
+
+	return "NULL"
+}
+
+func (s *Store) execDDL(ddl []string) error {
+	if len(ddl) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range ddl {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// sameColumnType compares a column type, as reported by the database, with
+// the expected type, ignoring case and any length, which is not reported by
+// every database.
+func sameColumnType(typ, expected string) bool {
+	if n := strings.IndexByte(expected, '('); n != -1 && !strings.Contains(typ, "(") {
+		expected = strings.TrimSpace(expected[:n])
+	}
+
+	return strings.EqualFold(typ, expected)
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type literalDefaults struct {
+	Dialect
+}
+
+func (literalDefaults) ColumnType(sqlType string) string {
+	return MySQL.ColumnType(sqlType)
+}
+
+func (literalDefaults) LiteralDefault(columnType string) bool {
+	return MySQL.LiteralDefault(columnType)
+}
+
+func TestMigrateDefaults(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	type migrateType struct {
+		ID   int
+		Name string
+	}
+	if err = s.Register(new(migrateType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	{
+		type migrateType struct {
+			ID    int
+			Name  string
+			Added int64
+			Text  string
+			Data  []byte
+			Key   string `store:"key,unique"`
+		}
+		expected := []string{
+			"ALTER TABLE [store.migrateType] ADD COLUMN [Added] BIGINT DEFAULT 0;",
+			"ALTER TABLE [store.migrateType] ADD COLUMN [Text] TEXT;",
+			"ALTER TABLE [store.migrateType] ADD COLUMN [Data] BLOB;",
+			"ALTER TABLE [store.migrateType] ADD COLUMN [key] VARCHAR(255) DEFAULT '';",
+			"CREATE UNIQUE INDEX [store.migrateType.key_key] ON [store.migrateType] ([key]);",
+		}
+		if ddl, err := NewWithDB(s.db, literalDefaults{SQLite}).PlanMigration(new(migrateType)); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		} else if !reflect.DeepEqual(ddl, expected) {
+			t.Errorf("expecting DDL %q, got %q", expected, ddl)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "migrate.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	type migrateType struct {
+		ID   int
+		Name string
+		Old  string
+	}
+	if err = s.Register(new(migrateType)); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if err = s.Set(&migrateType{0, "Alice", "Beep"}); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	s.Close()
+
+	{
+		type migrateType struct {
+			ID     int
+			Name   string
+			Added  int64
+			Before string
+		}
+		if s, err = New(path); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		expected := []string{
+			"ALTER TABLE [store.migrateType] ADD COLUMN [Added] INTEGER DEFAULT 0;",
+			"ALTER TABLE [store.migrateType] ADD COLUMN [Before] TEXT DEFAULT '';",
+		}
+		if ddl, err := s.PlanMigration(new(migrateType)); err != nil {
+			t.Fatalf("test 2: received unexpected error: %s", err)
+		} else if !reflect.DeepEqual(ddl, expected) {
+			t.Errorf("test 2: expecting DDL %q, got %q", expected, ddl)
+		}
+		s.Close()
+	}
+
+	{
+		type migrateType struct {
+			ID    int
+			Name  string
+			New   string `was:"Old"`
+			Added int64
+		}
+		if s, err = New(path); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		expected := []string{
+			"CREATE TABLE IF NOT EXISTS [store.migrateType_migrate]([ID] INTEGER PRIMARY KEY AUTOINCREMENT, [Name] TEXT, [New] TEXT, [Added] INTEGER);",
+			"INSERT INTO [store.migrateType_migrate] ([ID], [Name], [New], [Added]) SELECT [ID], [Name], [Old], 0 FROM [store.migrateType];",
+			"DROP TABLE [store.migrateType];",
+			"ALTER TABLE [store.migrateType_migrate] RENAME TO [store.migrateType];",
+		}
+		if ddl, err := s.PlanMigration(new(migrateType)); err != nil {
+			t.Fatalf("test 3: received unexpected error: %s", err)
+		} else if !reflect.DeepEqual(ddl, expected) {
+			t.Errorf("test 3: expecting DDL %q, got %q", expected, ddl)
+		}
+		if err = s.Register(new(migrateType)); err != nil {
+			t.Fatalf("test 4: received unexpected error: %s", err)
+		}
+		got := migrateType{ID: 1}
+		if err = s.Get(&got); err != nil {
+			t.Errorf("test 5: received unexpected error: %s", err)
+		} else if expected := (migrateType{1, "Alice", "Beep", 0}); got != expected {
+			t.Errorf("test 5: expecting %v, got %v", expected, got)
+		}
+		if ddl, err := s.PlanMigration(new(migrateType)); err != nil {
+			t.Errorf("test 6: received unexpected error: %s", err)
+		} else if len(ddl) != 0 {
+			t.Errorf("test 6: expecting no DDL, got %q", ddl)
+		}
+		s.Close()
+	}
+
+	{
+		type migrateType struct {
+			ID   int
+			Name int64
+		}
+		if s, err = New(path); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+		err = s.Register(new(migrateType))
+		var ce ColumnError
+		if !errors.Is(err, ErrIncompatibleColumn) || !errors.As(err, &ce) {
+			t.Errorf("test 7: expecting incompatible column error, got %v", err)
+		} else if ce.Column != "Name" || ce.Type != "TEXT" || ce.Expected != "INTEGER" {
+			t.Errorf("test 7: unexpected column error: %v", ce)
+		} else if err = s.Register(new(migrateType)); !errors.Is(err, ErrIncompatibleColumn) {
+			t.Errorf("test 8: expecting incompatible column error, got %v", err)
+		} else if err = s.Set(&migrateType{Name: 1}); err != ErrUnregisteredType {
+			t.Errorf("test 9: expecting error %s, got %v", ErrUnregisteredType, err)
+		}
+		s.Close()
+	}
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"reflect"
 )
@@ -12,19 +13,28 @@ type SortBy struct {
 
 type Search struct {
 	store  *Store
+	tx     *sql.Tx
 	i      interface{}
 	Sort   []SortBy
 	Filter Filter
+
+	// WithDeleted includes soft deleted records in the results.
+	WithDeleted bool
 }
 
 func (s *Store) NewSearch(i interface{}) *Search {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+	return s.newSearch(nil, i)
+}
+
+func (s *Store) newSearch(tx *sql.Tx, i interface{}) *Search {
 	if _, ok := s.types[typeName(i)]; !ok {
 		return nil
 	}
 	return &Search{
 		store: s,
+		tx:    tx,
 		i:     i,
 	}
 }
@@ -33,49 +43,77 @@ type PreparedSearch struct {
 	countStmt *sql.Stmt
 	getStmt   *sql.Stmt
 	vars      []interface{}
+	encodings []uint8
 	store     *Store
+	tx        *sql.Tx
+	name      string
+	filter    string
+	sort      []SortBy
 }
 
 func (s *Search) Prepare() (*PreparedSearch, error) {
+	return s.PrepareContext(context.Background())
+}
+
+func (s *Search) PrepareContext(ctx context.Context) (*PreparedSearch, error) {
 	var (
-		doneFirst    bool
-		sql, sqlVars string
-		vars         []interface{}
-		name         = typeName(s.i)
+		sql       string
+		vars      []interface{}
+		encodings []uint8
+		filter    string
+		name      = typeName(s.i)
 	)
+	s.store.typesMutex.RLock()
+	defer s.store.typesMutex.RUnlock()
+	prepare := s.store.db.PrepareContext
+	if s.tx != nil {
+		prepare = s.tx.PrepareContext
+	}
+	t := s.store.types[name]
 	if s.Filter != nil {
-		sql += "WHERE " + s.Filter.SQL() + " "
-		for _, i := range s.Filter.Vars() {
-			if v := reflect.ValueOf(i); v.Kind() != reflect.Ptr {
-				i = v.Addr().Interface()
+		if cf, ok := s.Filter.(columnFilter); ok {
+			for _, col := range cf.columns() {
+				if !t.hasColumn(col) {
+					return nil, ErrUnknownColumn
+				}
+			}
+		}
+		filter = s.Filter.SQL()
+		columns := filterVarColumns([]Filter{s.Filter})
+		for n, i := range s.Filter.Vars() {
+			if v := reflect.ValueOf(i); !v.IsValid() || v.Kind() == reflect.Ptr && v.IsNil() {
+				return nil, ErrInvalidType
+			} else if v.Kind() != reflect.Ptr {
+				p := reflect.New(v.Type())
+				p.Elem().Set(v)
+				i = p.Interface()
 			}
 			if !isValidType(i) {
 				return nil, ErrInvalidType
 			}
 			vars = append(vars, i)
+			encodings = append(encodings, s.store.varEncoding(&t, i, columns[n]))
 		}
 	}
-	s.store.mutex.Lock()
-	defer s.store.mutex.Unlock()
-	count, err := s.store.db.Prepare("SELECT COUNT(1) FROM [" + name + "] " + sql)
+	if scope := t.scope(s.WithDeleted); scope != "" && filter != "" {
+		filter = "(" + filter + ") AND " + scope
+	} else if scope != "" {
+		filter = scope
+	}
+	if filter != "" {
+		sql += "WHERE " + filter + " "
+	}
+	count, err := prepare(ctx, rebind(s.store.dialect, "SELECT COUNT(1) FROM ["+name+"] "+sql))
 	if err != nil {
 		return nil, err
 	}
-	t := s.store.types[name]
-	for pos, f := range t.fields {
-		if pos == t.primary {
-			continue
-		}
-		if doneFirst {
-			sqlVars += ", "
-		} else {
-			doneFirst = true
-		}
-		sqlVars += "[" + f.name + "]"
-	}
 	if len(s.Sort) > 0 {
 		sql += "ORDER BY "
 		for n, f := range s.Sort {
+			if !t.hasColumn(f.Column) {
+				count.Close()
+				return nil, ErrUnknownColumn
+			}
 			if n > 0 {
 				sql += ", "
 			}
@@ -87,45 +125,94 @@ func (s *Search) Prepare() (*PreparedSearch, error) {
 			}
 		}
 	}
-	get, err := s.store.db.Prepare("SELECT [" + t.fields[t.primary].name + "] FROM [" + name + "] " + sql + "LIMIT ? OFFSET ?;")
+	get, err := prepare(ctx, rebind(s.store.dialect, "SELECT "+t.columns()+" FROM ["+name+"] "+sql+"LIMIT ? OFFSET ?;"))
 	if err != nil {
+		count.Close()
 		return nil, err
 	}
 	return &PreparedSearch{
 		count,
 		get,
 		vars,
+		encodings,
 		s.store,
+		s.tx,
+		name,
+		filter,
+		s.Sort,
 	}, nil
 }
 
 func (p *PreparedSearch) Count() (int, error) {
-	p.store.mutex.Lock()
-	defer p.store.mutex.Unlock()
-	row := p.countStmt.QueryRow(p.getVars()...)
+	return p.CountContext(context.Background())
+}
+
+func (p *PreparedSearch) CountContext(ctx context.Context) (int, error) {
+	p.store.typesMutex.RLock()
+	defer p.store.typesMutex.RUnlock()
+	vars, err := p.getVars()
+	if err != nil {
+		return 0, err
+	}
+	row := p.countStmt.QueryRowContext(ctx, vars...)
 	var count int
-	err := row.Scan(&count)
+	err = row.Scan(&count)
 	return count, err
 }
 
 func (p *PreparedSearch) GetPage(is []interface{}, offset int) (int, error) {
+	return p.GetPageContext(context.Background(), is, offset)
+}
+
+func (p *PreparedSearch) GetPageContext(ctx context.Context, is []interface{}, offset int) (int, error) {
 	if len(is) == 0 {
 		return 0, nil
 	}
-	p.store.mutex.Lock()
-	defer p.store.mutex.Unlock()
-	rows, err := p.getStmt.Query(append(p.getVars(), len(is), offset)...)
+	p.store.typesMutex.RLock()
+	defer p.store.typesMutex.RUnlock()
+	vars, err := p.getVars()
+	if err != nil {
+		return 0, err
+	}
+	rows, err := p.getStmt.QueryContext(ctx, append(vars, len(is), offset)...)
 	if err != nil {
 		return 0, err
 	}
 	defer rows.Close()
-	return p.store.getPage(is, rows)
+	return p.store.getPage(ctx, p.tx, is, rows)
 }
 
-func (p *PreparedSearch) getVars() []interface{} {
+// Close closes the prepared statements of the search. Any Cursor created from
+// the search remains usable until it is closed.
+func (p *PreparedSearch) Close() error {
+	err := p.countStmt.Close()
+	if gerr := p.getStmt.Close(); err == nil {
+		err = gerr
+	}
+	return err
+}
+
+func (p *PreparedSearch) getVars() ([]interface{}, error) {
 	vars := make([]interface{}, len(p.vars), len(p.vars)+2)
 	for n, v := range p.vars {
-		vars[n] = reflect.ValueOf(v).Elem().Interface()
+		var err error
+		if vars[n], err = encodeValue(v, p.encodings[n]); err != nil {
+			return nil, err
+		}
+	}
+	return vars, nil
+}
+
+// varEncoding returns the encoding of a filter var, with times encoded as the
+// column they are compared with, when known, and otherwise with the time
+// encoding of the Store.
+func (s *Store) varEncoding(t *typeInfo, v interface{}, column string) uint8 {
+	enc := typeEncoding(reflect.TypeOf(v).Elem())
+	if !isTimeEncoding(enc) {
+		return enc
+	}
+	if f, ok := t.column(column); ok && isTimeEncoding(f.encoding) {
+		return f.encoding
 	}
-	return vars
+	return encodeUnix + uint8(s.timeEncoding)
 }
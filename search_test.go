@@ -94,4 +94,85 @@ func TestSearch(t *testing.T) {
 			}
 		}
 	}
+	if err = matchNumberPrepared.Close(); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if _, err = matchNumberPrepared.Count(); err == nil {
+		t.Errorf("test 2: expecting error using closed search")
+	}
+}
+
+func TestFilters(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatal(err)
+	}
+	values := []*testType{
+		{0, "One", 1},
+		{0, "Two", 2},
+		{0, "Three", 3},
+		{0, "Four", 4},
+		{0, "Five", 5},
+	}
+	for _, value := range values {
+		if err = s.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n, test := range []struct {
+		filter  Filter
+		results []*testType
+		err     error
+	}{
+		{Eq("Number", 3), []*testType{values[2]}, nil},
+		{NotEq("Number", 3), []*testType{values[0], values[1], values[3], values[4]}, nil},
+		{Lt("Number", 3), []*testType{values[0], values[1]}, nil},
+		{Lte("Number", 3), []*testType{values[0], values[1], values[2]}, nil},
+		{Gt("Number", 3), []*testType{values[3], values[4]}, nil},
+		{Gte("Number", 3), []*testType{values[2], values[3], values[4]}, nil},
+		{Between("Number", 2, 4), []*testType{values[1], values[2], values[3]}, nil},
+		{In("Data", "One", "Five"), []*testType{values[0], values[4]}, nil},
+		{NotIn("Data", "One", "Five"), []*testType{values[1], values[2], values[3]}, nil},
+		{Like("Data", "T%"), []*testType{values[1], values[2]}, nil},
+		{IsNull("Data"), []*testType{}, nil},
+		{IsNotNull("Data"), values, nil},
+		{Not(Or{Eq("Number", 1), Gt("Number", 2)}), []*testType{values[1]}, nil},
+		{And{Gt("Number", 1), Like("Data", "F%")}, []*testType{values[3], values[4]}, nil},
+		{Eq("Numbr", 3), nil, ErrUnknownColumn},
+		{Or{Eq("Number", 3), Not(IsNull("Dat"))}, nil, ErrUnknownColumn},
+		{Eq("Data", nil), nil, ErrInvalidType},
+		{In("Number", 1, (*int64)(nil)), nil, ErrInvalidType},
+	} {
+		search := s.NewSearch(new(testType))
+		search.Sort = []SortBy{{"ID", true}}
+		search.Filter = test.filter
+		ps, err := search.Prepare()
+		if err != test.err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.err, err)
+			continue
+		} else if err != nil {
+			continue
+		}
+		tts := make([]testType, 10)
+		vars := make([]interface{}, 10)
+		for i := range vars {
+			vars[i] = &tts[i]
+		}
+		found, err := ps.GetPage(vars, 0)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", n+1, err)
+		} else if found != len(test.results) {
+			t.Errorf("test %d: expecting %d results, got %d", n+1, len(test.results), found)
+		} else {
+			for m, tt := range test.results {
+				if !reflect.DeepEqual(tt, &tts[m]) {
+					t.Errorf("test %d-%d: expecting %v, got %v", n+1, m+1, tt, &tts[m])
+				}
+			}
+		}
+	}
 }
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// scope returns the condition that excludes soft deleted records from a query
+// on the type, or an empty string if it has no soft delete field.
+func (t *typeInfo) scope(withDeleted bool) string {
+	if withDeleted {
+		return ""
+	}
+
+	for _, f := range t.fields {
+		if f.role == roleDeleted {
+			return "[" + f.name + "] IS NULL"
+		}
+	}
+
+	return ""
+}
+
+// setDeleted sets the soft delete field of a record, returning the previous
+// value of the field.
+func (t *typeInfo) setDeleted(i interface{}, when time.Time) (field, reflect.Value) {
+	for _, f := range t.fields {
+		if f.role != roleDeleted {
+			continue
+		}
+
+		v := reflect.ValueOf(i).Elem().Field(f.pos)
+		old := reflect.New(v.Type()).Elem()
+
+		old.Set(v)
+
+		if v.Kind() != reflect.Ptr {
+			v.Set(reflect.ValueOf(when))
+		} else if when.IsZero() {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(&when))
+		}
+
+		return f, old
+	}
+
+	return field{}, reflect.Value{}
+}
+
+// softRemove sets the soft delete field of a record, returning true if the
+// record had not already been soft deleted.
+func (s *Store) softRemove(ctx context.Context, tx *sql.Tx, i interface{}, t *typeInfo, key []interface{}) (bool, error) {
+	f, old := t.setDeleted(i, time.Now().Round(0))
+
+	v, err := fieldValue(i, f)
+	if err == nil {
+		var r sql.Result
+
+		if r, err = stmt(tx, t.statements[softRemove]).ExecContext(ctx, append([]interface{}{v}, key...)...); err == nil {
+			var ra int64
+
+			if ra, err = r.RowsAffected(); err == nil && ra > 0 {
+				s.saved = append(s.saved, savedField{reflect.ValueOf(i).Elem().Field(f.pos), old})
+
+				return true, nil
+			}
+		}
+	}
+
+	reflect.ValueOf(i).Elem().Field(f.pos).Set(old)
+
+	return false, err
+}
+
+// softDeleted returns true if the record with the given key exists but has
+// been soft deleted.
+func (s *Store) softDeleted(ctx context.Context, tx *sql.Tx, t *typeInfo, key []interface{}) (bool, error) {
+	if t.statements[softRemove] == nil {
+		return false, nil
+	}
+
+	err := tx.QueryRowContext(ctx, rebind(s.dialect, "SELECT 1 FROM ["+t.typ.String()+"] WHERE "+t.keyWhere()+" LIMIT 1;"), key...).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Purge permanently removes the given records, including those of types with
+// a soft delete field, whether or not they have been soft deleted.
+func (s *Store) Purge(is ...interface{}) error {
+	return s.PurgeContext(context.Background(), is...)
+}
+
+func (s *Store) PurgeContext(ctx context.Context, is ...interface{}) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.remove(ctx, tx, is, true)
+	})
+}
+
+// Restore clears the soft delete field of the given soft deleted records.
+//
+// Returns ErrInvalidType for types without a soft delete field, and
+// ErrNotFound if a record does not exist or has not been soft deleted.
+func (s *Store) Restore(is ...interface{}) error {
+	return s.RestoreContext(context.Background(), is...)
+}
+
+func (s *Store) RestoreContext(ctx context.Context, is ...interface{}) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.restore(ctx, tx, is)
+	})
+}
+
+func (s *Store) restore(ctx context.Context, tx *sql.Tx, is []interface{}) error {
+	for _, i := range is {
+		if !isPointerStruct(i) {
+			return ErrNoPointerStruct
+		}
+
+		t, ok := s.types[typeName(i)]
+		if !ok {
+			return ErrUnregisteredType
+		} else if t.statements[restore] == nil {
+			return ErrInvalidType
+		}
+
+		key := t.GetID(i)
+
+		old, err := s.snapshot(ctx, tx, &t, key)
+		if err != nil {
+			return err
+		}
+
+		r, err := stmt(tx, t.statements[restore]).ExecContext(ctx, key...)
+		if err != nil {
+			return err
+		}
+
+		if ra, err := r.RowsAffected(); err != nil {
+			return err
+		} else if ra == 0 {
+			return ErrNotFound
+		} else if err = s.changed(ctx, tx, &t, Restored, key, old); err != nil {
+			return err
+		}
+
+		f, deleted := t.setDeleted(i, time.Time{})
+
+		s.saved = append(s.saved, savedField{reflect.ValueOf(i).Elem().Field(f.pos), deleted})
+	}
+
+	return nil
+}
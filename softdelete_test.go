@@ -0,0 +1,215 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+type softNote struct {
+	ID      int
+	Text    string
+	Deleted time.Time `store:"deleted,softdelete,time=unixnano"`
+}
+
+type softAuthor struct {
+	ID      int
+	Name    string
+	Deleted *time.Time `store:"deleted,softdelete"`
+}
+
+type softBook struct {
+	ID     int
+	Title  string
+	Author *softAuthor `delete:"set-null"`
+}
+
+type badSoftDeleteType struct {
+	ID      int
+	Deleted int64 `store:"deleted,softdelete"`
+}
+
+type badSoftDeleteCount struct {
+	ID int
+	A  time.Time `store:"a,softdelete"`
+	B  time.Time `store:"b,softdelete"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(softNote)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	notes := []softNote{{Text: "A"}, {Text: "B"}, {Text: "C"}}
+	if err = s.Set(&notes[0], &notes[1], &notes[2]); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	before := time.Now()
+	if err = s.Remove(&notes[1]); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if notes[1].Deleted.Before(before) {
+		t.Fatalf("test 1: expecting deleted time after %s, got %s", before, notes[1].Deleted)
+	}
+	deleted := notes[1].Deleted
+	if got := (softNote{ID: 2}); s.Get(&got) != nil || got.ID != 0 {
+		t.Errorf("test 2: expecting soft deleted record to not be found, got %v", got)
+	}
+	if got := (softNote{ID: 2}); s.GetWith(&got, WithDeleted()) != nil || got.Text != "B" || !got.Deleted.Equal(deleted) {
+		t.Errorf("test 3: expecting soft deleted record with deleted time %s, got %v", deleted, got)
+	}
+	if c, err := s.Count(new(softNote)); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if c != 2 {
+		t.Errorf("test 4: expecting count 2, got %d", c)
+	}
+	page := make([]softNote, 3)
+	if n, err := s.GetPage([]interface{}{&page[0], &page[1], &page[2]}, 0); err != nil {
+		t.Errorf("test 5: received unexpected error: %s", err)
+	} else if n != 2 || page[0].Text != "A" || page[1].Text != "C" {
+		t.Errorf("test 5: expecting records A and C, got %d records: %v", n, page[:n])
+	}
+	for n, test := range []struct {
+		filter      Filter
+		withDeleted bool
+		count       int
+	}{
+		{nil, false, 2},
+		{nil, true, 3},
+		{Or{Eq("Text", "A"), Eq("Text", "B")}, false, 1},
+		{Or{Eq("Text", "A"), Eq("Text", "B")}, true, 2},
+	} {
+		search := s.NewSearch(new(softNote))
+		search.Filter = test.filter
+		search.WithDeleted = test.withDeleted
+		if p, err := search.Prepare(); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+6, err)
+		} else if c, err := p.Count(); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+6, err)
+		} else if c != test.count {
+			t.Errorf("test %d: expecting count %d, got %d", n+6, test.count, c)
+		}
+	}
+	notes[1].Text = "Changed"
+	if err = s.Update(&notes[1]); err != ErrNotFound {
+		t.Errorf("test 10: expecting error %s, got %v", ErrNotFound, err)
+	} else if err = s.Set(&notes[1]); err != ErrNotFound {
+		t.Errorf("test 10: expecting error %s, got %v", ErrNotFound, err)
+	}
+	if err = s.Restore(&notes[1]); err != nil {
+		t.Errorf("test 11: received unexpected error: %s", err)
+	} else if !notes[1].Deleted.IsZero() {
+		t.Errorf("test 11: expecting zero deleted time, got %s", notes[1].Deleted)
+	} else if got := (softNote{ID: 2}); s.Get(&got) != nil || got.Text != "B" || !got.Deleted.IsZero() {
+		t.Errorf("test 11: expecting restored record, got %v", got)
+	}
+	if err = s.Restore(&notes[1]); err != ErrNotFound {
+		t.Errorf("test 12: expecting error %s, got %v", ErrNotFound, err)
+	}
+	if err = s.Remove(&notes[0]); err != nil {
+		t.Errorf("test 13: received unexpected error: %s", err)
+	} else if err = s.Purge(&notes[0], &notes[2]); err != nil {
+		t.Errorf("test 13: received unexpected error: %s", err)
+	} else if got := (softNote{ID: 1}); s.GetWith(&got, WithDeleted()) != nil || got.ID != 0 {
+		t.Errorf("test 13: expecting purged record to not be found, got %v", got)
+	}
+	var total int
+	if err = s.db.QueryRow("SELECT COUNT(1) FROM [store.softNote];").Scan(&total); err != nil {
+		t.Errorf("test 14: received unexpected error: %s", err)
+	} else if total != 1 {
+		t.Errorf("test 14: expecting 1 row, got %d", total)
+	}
+	if err = s.Restore(&testType{ID: 1}); err != ErrUnregisteredType {
+		t.Errorf("test 15: expecting error %s, got %v", ErrUnregisteredType, err)
+	}
+	if err = s.Remove(&notes[1], &testType{ID: 1}); err != ErrUnregisteredType {
+		t.Errorf("test 16: expecting error %s, got %v", ErrUnregisteredType, err)
+	} else if !notes[1].Deleted.IsZero() {
+		t.Errorf("test 16: expecting deleted time to be reset, got %s", notes[1].Deleted)
+	}
+}
+
+func TestSoftDeleteReferences(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(softBook), new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	book := softBook{Title: "Book", Author: &softAuthor{Name: "Author"}}
+	if err = s.Set(&book); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Remove(book.Author); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if book.Author.Deleted == nil {
+		t.Fatalf("test 1: expecting deleted time to be set")
+	}
+	got := softBook{ID: book.ID}
+	if err = s.Get(&got); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if got.Author == nil || got.Author.Name != "Author" || got.Author.Deleted == nil {
+		t.Errorf("test 2: expecting soft deleted author to be retrieved, got %v", got.Author)
+	}
+	c, err := NewCollection[softAuthor](s)
+	if err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	} else if n, err := c.Count(); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if n != 0 {
+		t.Errorf("test 3: expecting count 0, got %d", n)
+	}
+	if err = c.Restore(book.Author); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if book.Author.Deleted != nil {
+		t.Errorf("test 4: expecting nil deleted time, got %s", book.Author.Deleted)
+	} else if a, err := c.Get(book.Author.ID); err != nil || a.Name != "Author" {
+		t.Errorf("test 4: expecting restored author, got %v, %v", a, err)
+	}
+	if err = s.Restore(&testType{}); err != ErrInvalidType {
+		t.Errorf("test 5: expecting error %s, got %v", ErrInvalidType, err)
+	}
+	if err = c.Purge(book.Author); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if got = (softBook{ID: book.ID}); s.Get(&got) != nil || got.Author != nil {
+		t.Errorf("test 6: expecting author to be cleared, got %v", got.Author)
+	}
+}
+
+func TestSoftDeleteSQL(t *testing.T) {
+	_, queries := buildSQL(PostgreSQL, "store.softNote", new(softNote), []field{
+		{pos: 0, name: "ID"},
+		{pos: 1, name: "Text"},
+		{pos: 2, name: "deleted", role: roleDeleted},
+	}, []int{0}, true)
+	for n, test := range []struct {
+		query    int
+		expected string
+	}{
+		{get, `SELECT "ID", "Text", "deleted" FROM "store.softNote" WHERE "ID" = $1 AND "deleted" IS NULL LIMIT 1;`},
+		{update, `UPDATE "store.softNote" SET "Text" = $1 WHERE "ID" = $2 AND "deleted" IS NULL;`},
+		{getPage, `SELECT "ID", "Text", "deleted" FROM "store.softNote" WHERE "deleted" IS NULL ORDER BY "ID" LIMIT $1 OFFSET $2;`},
+		{count, `SELECT COUNT(1) FROM "store.softNote" WHERE "deleted" IS NULL;`},
+		{upsert, ""},
+		{softRemove, `UPDATE "store.softNote" SET "deleted" = $1 WHERE "ID" = $2 AND "deleted" IS NULL;`},
+		{restore, `UPDATE "store.softNote" SET "deleted" = NULL WHERE "ID" = $1 AND "deleted" IS NOT NULL;`},
+	} {
+		if queries[test.query] != test.expected {
+			t.Errorf("test %d: expecting SQL %q, got %q", n+1, test.expected, queries[test.query])
+		}
+	}
+	for n, i := range []interface{}{new(badSoftDeleteType), new(badSoftDeleteCount)} {
+		s, err := newTestStore()
+		if err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+8, err)
+		}
+		if err = s.Register(i); err != ErrInvalidTag {
+			t.Errorf("test %d: expecting error %s, got %v", n+8, ErrInvalidTag, err)
+		}
+		s.Close()
+	}
+}
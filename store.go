@@ -2,8 +2,10 @@
 package store // import "vimagination.zapto.org/store"
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"net/url"
 	"reflect"
 	"strings"
 	"sync"
@@ -19,53 +21,153 @@ const (
 	remove
 	getPage
 	count
+	addKey
+	upsert
+	exists
+	softRemove
+	restore
+)
+
+const (
+	setUpsert uint8 = iota
+	setInsert
+	setUpdate
 )
 
 type field struct {
 	isStruct bool
 	pos      int
 	name     string
+	was      string
+	encoding uint8
+	onDelete uint8
+	ref      string
+	refKey   string
+	refType  string
+	lazy     bool
+	unique   bool
+	indexes  []string
+	notNull  bool
+	nullable bool
+	def      string
+	check    string
+	role     uint8
 }
 
 type typeInfo struct {
-	primary    int
+	typ        reflect.Type
+	keys       []int
+	auto       bool
 	fields     []field
+	children   []child
+	indexes    []index
 	statements []*sql.Stmt
+	history    []*sql.Stmt
+	cache      *cache
 }
 
 type Store struct {
-	db    *sql.DB
-	types map[string]typeInfo
-	mutex sync.Mutex
+	db           *sql.DB
+	dialect      Dialect
+	types        map[string]typeInfo
+	typesMutex   sync.RWMutex
+	timeEncoding TimeEncoding
+	mutex        ctxMutex
+	saved        []savedField
+
+	subscriptions map[string][]*subscription
+	pending       []Event
+	subsMutex     sync.Mutex
 }
 
+// New opens an SQLite database, creating it if necessary.
+//
+// File databases are switched to WAL mode so that readers do not block, or get
+// blocked by, the single writer. As each connection to an in-memory database
+// opens its own database, in-memory databases, including file URIs with a
+// memory mode, are limited to a single connection, so concurrent operations on
+// them wait for each other.
 func New(dataSourceName string) (*Store, error) {
 	db, err := sql.Open("sqlite3", dataSourceName)
 	if err != nil {
 		return nil, err
 	}
 
+	if inMemory(dataSourceName) {
+		db.SetMaxOpenConns(1)
+	} else if _, err = db.Exec("PRAGMA journal_mode = WAL;"); err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return NewWithDB(db, SQLite), nil
+}
+
+// inMemory determines whether a data source name refers to an in-memory
+// database, either by name or as a URI with a memory mode.
+func inMemory(dataSourceName string) bool {
+	if dataSourceName == "" || dataSourceName == ":memory:" {
+		return true
+	} else if !strings.HasPrefix(dataSourceName, "file:") {
+		return false
+	}
+
+	path, query, _ := strings.Cut(strings.TrimPrefix(dataSourceName, "file:"), "?")
+	if path == ":memory:" {
+		return true
+	}
+
+	values, err := url.ParseQuery(query)
+
+	return err == nil && values.Get("mode") == "memory"
+}
+
+// NewWithDB creates a Store using an existing database connection, generating
+// SQL for the given Dialect.
+func NewWithDB(db *sql.DB, d Dialect) *Store {
 	return &Store{
-		db:    db,
-		types: make(map[string]typeInfo),
-	}, nil
+		db:      db,
+		dialect: d,
+		types:   make(map[string]typeInfo),
+		mutex:   make(ctxMutex, 1),
+	}
 }
 
 func (s *Store) Close() error {
+	s.typesMutex.Lock()
+	defer s.typesMutex.Unlock()
+
+	s.unsubscribeAll()
+
 	err := s.db.Close()
 	s.db = nil
 
 	return err
 }
 
+// Register creates, or migrates, the tables for the given types, along with
+// those of their nested structs.
+//
+// The store tag of a field gives its column name followed by comma separated
+// options. Commas within single quotes or parentheses, such as those in the
+// values of the default and check options, do not separate options. Unknown
+// options return ErrInvalidTag.
 func (s *Store) Register(is ...interface{}) error {
-	if s.db == nil {
-		return ErrDBClosed
-	}
+	return s.register(is, false)
+}
 
+func (s *Store) register(is []interface{}, audit bool) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.typesMutex.Lock()
+	defer s.typesMutex.Unlock()
+
+	if s.db == nil {
+		return ErrDBClosed
+	}
+
 	for _, i := range is {
 		if !isPointerStruct(i) {
 			return ErrNoPointerStruct
@@ -74,6 +176,12 @@ func (s *Store) Register(is ...interface{}) error {
 		if err := s.defineType(i); err != nil {
 			return err
 		}
+
+		if audit {
+			if err := s.auditType(i); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -87,11 +195,110 @@ func (s *Store) defineType(i interface{}) error {
 
 	s.types[name] = typeInfo{}
 
+	t, err := s.typeFields(i, s.defineType)
+	if err != nil {
+		delete(s.types, name)
+
+		return err
+	}
+
+	s.types[name] = typeInfo{
+		keys: t.keys,
+		auto: t.auto,
+	}
+
+	if err := s.prepareType(name, i, &t); err != nil {
+		delete(s.types, name)
+		closeAll(t.statements)
+
+		for _, c := range t.children {
+			closeAll(c.statements)
+		}
+
+		return err
+	}
+
+	s.types[name] = t
+
+	return nil
+}
+
+func (s *Store) prepareType(name string, i interface{}, t *typeInfo) error {
+	ddl, err := s.schemaSQL(name, i, t)
+	if err != nil {
+		return err
+	}
+
+	if err := s.execDDL(ddl); err != nil {
+		return err
+	}
+
+	_, queries := buildSQL(s.dialect, name, i, t.fields, t.keys, t.auto)
+
+	if t.statements, err = s.prepareAll(queries); err != nil {
+		return err
+	}
+
+	for n := range t.children {
+		c := &t.children[n]
+
+		_, queries := buildChildSQL(s.dialect, c.table, c.parentType, c.sqlType)
+
+		if c.statements, err = s.prepareAll(queries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) prepareAll(queries []string) ([]*sql.Stmt, error) {
+	statements := make([]*sql.Stmt, len(queries))
+
+	for n, query := range queries {
+		if query == "" {
+			continue
+		}
+
+		stmt, err := s.db.Prepare(query)
+		if err != nil {
+			closeAll(statements)
+
+			return nil, err
+		}
+
+		statements[n] = stmt
+	}
+
+	return statements, nil
+}
+
+func closeAll(statements []*sql.Stmt) {
+	for _, stmt := range statements {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+func (s *Store) typeFields(i interface{}, nested func(interface{}) error) (typeInfo, error) {
 	v := reflect.ValueOf(i).Elem()
+	name := typeName(i)
 	numFields := v.Type().NumField()
 	fields := make([]field, 0, numFields)
-	id := 0
-	idType := 0
+
+	keyPos, err := keyFields(v.Type())
+	if err != nil {
+		return typeInfo{}, err
+	}
+
+	var (
+		keys     = make([]int, len(keyPos))
+		keyType  = getType(i, keyPos[0])
+		children []child
+		versions int
+		deleted  int
+	)
 
 	for n := 0; n < numFields; n++ {
 		f := v.Type().Field(n)
@@ -99,21 +306,25 @@ func (s *Store) defineType(i interface{}) error {
 			continue
 		}
 
-		fieldName := f.Name
-
-		if fn := f.Tag.Get("store"); fn != "" {
-			fieldName = fn
-		}
+		fieldName, opts := parseTag(f)
 
 		if fieldName == "-" { // Skip field
 			continue
+		} else if !validOptions(opts) {
+			return typeInfo{}, ErrInvalidTag
 		}
 
 		tmp := strings.ToLower(fieldName)
 
 		for _, tf := range fields {
 			if strings.ToLower(tf.name) == tmp {
-				return ErrDuplicateColumn
+				return typeInfo{}, ErrDuplicateColumn
+			}
+		}
+
+		for _, c := range children {
+			if strings.ToLower(c.name) == tmp {
+				return typeInfo{}, ErrDuplicateColumn
 			}
 		}
 
@@ -122,171 +333,377 @@ func (s *Store) defineType(i interface{}) error {
 		var iface interface{}
 
 		if isPointer {
-			iface = v.Field(n).Interface()
+			iface = reflect.New(f.Type.Elem()).Interface()
 		} else {
 			iface = v.Field(n).Addr().Interface()
 		}
 
 		isStruct := false
 
-		if isPointerStruct(iface) {
-			if _, ok := iface.(*time.Time); !ok {
-				if err := s.defineType(iface); err != nil {
-					return err
-				}
+		var ref, refKey, refType string
+
+		if isPointerStruct(iface) && !isValidType(iface) {
+			if err := nested(iface); err != nil {
+				return typeInfo{}, err
+			}
+
+			isStruct = true
+			ref = typeName(iface)
 
-				isStruct = true
+			if refKey, refType, err = referencedKey(reflect.TypeOf(iface).Elem()); err != nil {
+				return typeInfo{}, err
 			}
 		} else if !isValidType(iface) {
+			if f.Type.Kind() == reflect.Map {
+				return typeInfo{}, ErrMapField
+			} else if f.Type.Kind() != reflect.Slice {
+				continue
+			} else if len(keyPos) > 1 {
+				return typeInfo{}, ErrCompositeKey
+			}
+
+			c, err := sliceChild(name, keyType, fieldName, n, f, nested)
+			if err != nil {
+				return typeInfo{}, err
+			} else if c.onDelete, err = deletePolicy(f.Tag.Get("delete")); err != nil {
+				return typeInfo{}, err
+			} else if hasColumnOptions(opts) || f.Tag.Get("index") != "" {
+				return typeInfo{}, ErrInvalidTag
+			} else if c.encoding, err = s.fieldEncoding(c.encoding, opts); err != nil {
+				return typeInfo{}, err
+			} else if isTimeEncoding(c.encoding) && f.Tag.Get("type") == "" {
+				c.sqlType = timeSQLType(c.encoding)
+			}
+
+			c.lazy = hasOption(opts, "lazy")
+			children = append(children, c)
+
 			continue
 		}
 
-		if isValidKeyType(iface) {
-			if idType < 3 && f.Tag.Get("key") == "1" {
-				idType = 3
-				id = len(fields)
-			} else if idType < 2 && strings.ToLower(fieldName) == "id" {
-				idType = 2
-				id = len(fields)
-			} else if idType < 1 {
-				idType = 1
-				id = len(fields)
+		isKey := false
+
+		for k, pos := range keyPos {
+			if pos == n {
+				keys[k] = len(fields)
+				isKey = true
 			}
 		}
 
+		onDelete, err := deletePolicy(f.Tag.Get("delete"))
+		if err != nil {
+			return typeInfo{}, err
+		}
+
+		encoding, err := s.fieldEncoding(typeEncoding(reflect.TypeOf(iface).Elem()), opts)
+		if err != nil {
+			return typeInfo{}, err
+		}
+
+		role, err := fieldRole(opts, reflect.TypeOf(iface).Elem())
+		if err != nil || role != roleNone && (isPointer && role != roleDeleted || isKey) {
+			return typeInfo{}, ErrInvalidTag
+		}
+
+		if role == roleVersion {
+			versions++
+		} else if role == roleDeleted {
+			deleted++
+		}
+
+		notNull := hasOption(opts, "notnull")
+		if notNull && isStruct && (onDelete == deleteSetNull || onDelete == deleteCascade) {
+			return typeInfo{}, ErrInvalidTag
+		}
+
 		fields = append(fields, field{
-			isStruct,
-			n,
-			fieldName,
+			isStruct: isStruct,
+			pos:      n,
+			name:     fieldName,
+			was:      f.Tag.Get("was"),
+			encoding: encoding,
+			onDelete: onDelete,
+			ref:      ref,
+			refKey:   refKey,
+			refType:  refType,
+			lazy:     hasOption(opts, "lazy"),
+			unique:   hasOption(opts, "unique"),
+			indexes:  indexNames(f.Tag.Get("index")),
+			notNull:  notNull,
+			nullable: isPointer || nullTypes[reflect.TypeOf(iface).Elem()] != "",
+			def:      optionValue(opts, "default"),
+			check:    optionValue(opts, "check"),
+			role:     role,
 		})
 	}
 
-	if idType == 0 {
-		return ErrNoKey
+	if versions > 1 || deleted > 1 {
+		return typeInfo{}, ErrInvalidTag
 	}
 
-	s.types[name] = typeInfo{
-		primary: id,
+	indexes, err := typeIndexes(name, fields)
+	if err != nil {
+		return typeInfo{}, err
 	}
 
-	// create statements
+	kt := v.Type().Field(keyPos[0]).Type
+	if kt.Kind() == reflect.Ptr {
+		kt = kt.Elem()
+	}
+
+	return typeInfo{
+		typ:      v.Type(),
+		keys:     keys,
+		auto:     len(keys) == 1 && isIntKeyType(kt) && typeEncoding(kt) == encodeNone,
+		fields:   fields,
+		children: children,
+		indexes:  indexes,
+	}, nil
+}
+
+func buildSQL(d Dialect, name string, i interface{}, fields []field, keys []int, auto bool) (string, []string) {
 	var (
 		sqlVars, sqlParams, setSQLParams, tableVars string
-		doneFirst, doneFirstNonKey                  bool
+		version, deleted                            string
+		doneFirstNonKey, tracked, unique            bool
+		columns, keyColumns                         []string
 	)
 
 	for pos, f := range fields {
-		if doneFirst {
+		if pos > 0 {
 			tableVars += ", "
-		} else {
-			doneFirst = true
 		}
 
-		if pos != id {
+		isKey := false
+
+		for _, k := range keys {
+			isKey = isKey || k == pos
+		}
+
+		if !isKey {
 			if doneFirstNonKey {
 				sqlVars += ", "
-				setSQLParams += ", "
 				sqlParams += ", "
 			} else {
 				doneFirstNonKey = true
 			}
 		}
 
-		var varType string
+		varType := columnType(i, f, isKey)
 
-		if f.isStruct {
-			varType = "INTEGER"
+		tableVars += "[" + f.name + "] "
+
+		if auto && isKey {
+			tableVars += d.AutoIncrementKey(varType)
 		} else {
-			varType = getType(i, f.pos)
+			tableVars += d.ColumnType(varType) + f.constraints()
 		}
 
-		tableVars += "[" + f.name + "] " + varType
-
-		if pos == id {
-			tableVars += " PRIMARY KEY AUTOINCREMENT"
-		} else {
+		if !isKey {
 			sqlVars += "[" + f.name + "]"
-			setSQLParams += "[" + f.name + "] = ?"
 			sqlParams += "?"
+			columns = append(columns, f.name)
+		}
+
+		if !isKey && f.role != roleCreated && f.role != roleDeleted {
+			if setSQLParams != "" {
+				setSQLParams += ", "
+			}
+
+			setSQLParams += "[" + f.name + "] = ?"
 		}
+
+		if f.role == roleVersion {
+			version = " AND [" + f.name + "] = ?"
+		} else if f.role == roleDeleted {
+			deleted = "[" + f.name + "]"
+		}
+
+		tracked = tracked || f.role != roleNone && f.role != roleUpdated
+		unique = unique || f.unique
 	}
 
-	statements := make([]*sql.Stmt, 6)
+	var key, where string
+
+	for n, k := range keys {
+		if n > 0 {
+			key += ", "
+			where += " AND "
+		}
 
-	sql := "CREATE TABLE IF NOT EXISTS [" + name + "](" + tableVars + ");"
+		key += "[" + fields[k].name + "]"
+		where += "[" + fields[k].name + "] = ?"
+		keyColumns = append(keyColumns, fields[k].name)
+	}
 
-	_, err := s.db.Exec(sql)
-	if err != nil {
-		return err
+	if !auto {
+		tableVars += ", PRIMARY KEY (" + key + ")"
 	}
 
-	sql = "INSERT INTO [" + name + "] (" + sqlVars + ") VALUES (" + sqlParams + ");"
+	for _, f := range fields {
+		if f.isStruct && f.onDelete != deleteOrphan {
+			tableVars += ", FOREIGN KEY ([" + f.name + "]) REFERENCES [" + f.ref + "]([" + f.refKey + "]) ON DELETE "
 
-	stmt, err := s.db.Prepare(sql)
-	if err != nil {
-		return err
+			if f.onDelete == deleteRestrict {
+				tableVars += "RESTRICT"
+			} else {
+				tableVars += "SET NULL"
+			}
+		}
 	}
 
-	statements[add] = stmt
+	queries := make([]string, restore+1)
+	queries[addKey] = insertKey(name, keyColumns, columns) + ";"
 
-	sql = "SELECT " + sqlVars + " FROM [" + name + "] WHERE [" + fields[id].name + "] = ? LIMIT 1;"
+	if auto {
+		if sqlVars == "" {
+			queries[add] = "INSERT INTO [" + name + "] " + d.DefaultValues()
+		} else {
+			queries[add] = "INSERT INTO [" + name + "] (" + sqlVars + ") VALUES (" + sqlParams + ")"
+		}
 
-	stmt, err = s.db.Prepare(sql)
-	if err != nil {
-		return err
+		if d.ReturningID() {
+			queries[add] += " RETURNING " + key
+		}
+
+		queries[add] += ";"
+	} else {
+		queries[add] = queries[addKey]
 	}
 
-	statements[get] = stmt
+	selectVars := key
 
-	sql = "UPDATE [" + name + "] SET " + setSQLParams + " WHERE [" + fields[id].name + "] = ?;"
+	if sqlVars != "" {
+		selectVars += ", " + sqlVars
+	}
 
-	stmt, err = s.db.Prepare(sql)
-	if err != nil {
-		return err
+	if setSQLParams == "" {
+		setSQLParams = "[" + keyColumns[0] + "] = [" + keyColumns[0] + "]"
 	}
 
-	statements[update] = stmt
+	var scope, scoped string
 
-	sql = "DELETE FROM [" + name + "] WHERE [" + fields[id].name + "] = ?;"
+	if deleted != "" {
+		scope = " AND " + deleted + " IS NULL"
+		scoped = " WHERE " + deleted + " IS NULL"
+	}
 
-	stmt, err = s.db.Prepare(sql)
-	if err != nil {
-		return err
+	queries[get] = "SELECT " + selectVars + " FROM [" + name + "] WHERE " + where + scope + " LIMIT 1;"
+	queries[update] = "UPDATE [" + name + "] SET " + setSQLParams + " WHERE " + where + version + scope + ";"
+	queries[remove] = "DELETE FROM [" + name + "] WHERE " + where + ";"
+	queries[getPage] = "SELECT " + selectVars + " FROM [" + name + "]" + scoped + " ORDER BY " + key + " LIMIT ? OFFSET ?;"
+	queries[count] = "SELECT COUNT(1) FROM [" + name + "]" + scoped + ";"
+	// MySQL updates the existing record when any unique index, not just the
+	// key, would be violated, so records with unique columns are instead
+	// updated, and inserted if not found.
+	if !tracked && !unique {
+		queries[upsert] = d.Upsert(name, keyColumns, columns)
 	}
 
-	statements[remove] = stmt
+	queries[exists] = "SELECT 1 FROM [" + name + "] WHERE " + where + scope + " LIMIT 1;"
 
-	sql = "SELECT [" + fields[id].name + "] FROM [" + name + "] ORDER BY [" + fields[id].name + "] LIMIT ? OFFSET ?;"
+	if deleted != "" {
+		queries[softRemove] = "UPDATE [" + name + "] SET " + deleted + " = ? WHERE " + where + scope + ";"
+		queries[restore] = "UPDATE [" + name + "] SET " + deleted + " = NULL WHERE " + where + " AND " + deleted + " IS NOT NULL;"
+	}
 
-	stmt, err = s.db.Prepare(sql)
-	if err != nil {
+	for n, query := range queries {
+		queries[n] = rebind(d, query)
+	}
+
+	return rebind(d, "CREATE TABLE IF NOT EXISTS ["+name+"]("+tableVars+");"), queries
+}
+
+// Set stores the given records, updating those whose key is already in use and
+// inserting the rest, preserving any non-zero key.
+//
+// Records with a zero key are inserted with a newly assigned key, which is set
+// on the record, and is reset to zero if the transaction is rolled back.
+//
+// Returns ErrNotFound for a record whose key belongs to a soft deleted record,
+// which must first be restored with Restore.
+func (s *Store) Set(is ...interface{}) error {
+	return s.SetContext(context.Background(), is...)
+}
+
+func (s *Store) SetContext(ctx context.Context, is ...interface{}) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.setAll(ctx, tx, is, setUpsert)
+	})
+}
+
+// Insert stores the given records as new records, returning ErrExists if the
+// non-zero key of any of them is already in use.
+//
+// Nested structs are stored as with Set.
+func (s *Store) Insert(is ...interface{}) error {
+	return s.InsertContext(context.Background(), is...)
+}
+
+func (s *Store) InsertContext(ctx context.Context, is ...interface{}) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.setAll(ctx, tx, is, setInsert)
+	})
+}
+
+// Update stores the given records over existing records, returning ErrNotFound
+// if any of them do not exist.
+//
+// Nested structs are stored as with Set.
+func (s *Store) Update(is ...interface{}) error {
+	return s.UpdateContext(context.Background(), is...)
+}
+
+func (s *Store) UpdateContext(ctx context.Context, is ...interface{}) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.setAll(ctx, tx, is, setUpdate)
+	})
+}
+
+// Upsert is the same as Set, updating records with a key that is already in use
+// and otherwise inserting them with the key given.
+func (s *Store) Upsert(is ...interface{}) error {
+	return s.UpsertContext(context.Background(), is...)
+}
+
+func (s *Store) UpsertContext(ctx context.Context, is ...interface{}) error {
+	return s.SetContext(ctx, is...)
+}
+
+func (s *Store) inTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	if err := s.mutex.LockContext(ctx); err != nil {
 		return err
 	}
 
-	statements[getPage] = stmt
+	defer s.mutex.Unlock()
+
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
 
-	sql = "SELECT COUNT(1) FROM [" + name + "];"
+	if s.db == nil {
+		return ErrDBClosed
+	}
 
-	stmt, err = s.db.Prepare(sql)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	statements[count] = stmt
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		s.finish(false)
 
-	s.types[name] = typeInfo{
-		primary:    id,
-		fields:     fields,
-		statements: statements,
+		return err
 	}
 
-	return nil
-}
+	err = tx.Commit()
 
-func (s *Store) Set(is ...interface{}) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.finish(err == nil)
+
+	return err
+}
 
+func (s *Store) setAll(ctx context.Context, tx *sql.Tx, is []interface{}, mode uint8) error {
 	var toSet []interface{}
 
 	for _, i := range is {
@@ -297,7 +714,7 @@ func (s *Store) Set(is ...interface{}) error {
 
 		toSet = toSet[:0]
 
-		if err := s.set(i, &t, &toSet); err != nil {
+		if err := s.set(ctx, tx, i, &t, &toSet, mode); err != nil {
 			return err
 		}
 	}
@@ -305,7 +722,7 @@ func (s *Store) Set(is ...interface{}) error {
 	return nil
 }
 
-func (s *Store) set(i interface{}, t *typeInfo, toSet *[]interface{}) error {
+func (s *Store) set(ctx context.Context, tx *sql.Tx, i interface{}, t *typeInfo, toSet *[]interface{}, mode uint8) error {
 	for _, oi := range *toSet {
 		if oi == i {
 			return nil
@@ -313,147 +730,550 @@ func (s *Store) set(i interface{}, t *typeInfo, toSet *[]interface{}) error {
 	}
 
 	(*toSet) = append(*toSet, i)
-	id := t.GetID(i)
-	isUpdate := id != 0
-	vars := make([]interface{}, 0, len(t.fields))
+
+	if err := beforeSet(ctx, i); err != nil {
+		return err
+	}
+
+	hasID := t.hasID(i)
+
+	if !hasID {
+		if mode == setUpdate {
+			return ErrNotFound
+		} else if !t.auto {
+			return ErrNoKey
+		}
+	}
+
+	var (
+		now     = time.Now().Round(0)
+		version = t.version(i)
+		vars    = make([]interface{}, 0, len(t.fields))
+	)
+
+	s.saveTracked(i, t)
+	t.setUpdated(i, now)
 
 	for pos, f := range t.fields {
-		if pos == t.primary {
+		if t.isKey(pos) {
 			continue
 		}
 
 		if f.isStruct {
 			ni := getFieldPointer(i, f.pos)
+			if reflect.ValueOf(ni).IsNil() {
+				vars = append(vars, nil)
+
+				continue
+			}
+
 			nt := s.types[typeName(ni)]
 
-			err := s.set(ni, &nt, toSet)
+			err := s.set(ctx, tx, ni, &nt, toSet, setUpsert)
 			if err != nil {
 				return err
 			}
 
-			vars = append(vars, getField(ni, nt.fields[nt.primary].pos))
+			vars = append(vars, nt.GetID(ni)[0])
+		} else if f.role == roleVersion {
+			vars = append(vars, version+1)
 		} else {
-			vars = append(vars, getField(i, f.pos))
+			v, err := fieldValue(i, f)
+			if err != nil {
+				return err
+			}
+
+			vars = append(vars, v)
 		}
 	}
 
-	if isUpdate {
-		r, err := t.statements[update].Exec(append(vars, id)...)
+	if !hasID {
+		if err := t.setCreated(i, vars, now); err != nil {
+			return err
+		}
+
+		id, err := s.insert(ctx, tx, t, vars)
 		if err != nil {
+			return s.uniqueError(typeName(i), t, err)
+		}
+
+		for _, k := range t.keys {
+			s.save(i, t.fields[k].pos)
+		}
+
+		if err = t.SetID(i, []interface{}{id}); err != nil {
+			return err
+		} else if err = s.changed(ctx, tx, t, Inserted, t.GetID(i), nil); err != nil {
 			return err
 		}
 
-		if ra, err := r.RowsAffected(); err != nil {
+		t.setVersion(i, version+1)
+
+		if err := s.setChildren(ctx, tx, i, t, id, toSet); err != nil {
 			return err
-		} else if ra > 0 {
-			return nil
-		} // id wasn't found, so insert...
+		}
+
+		return afterSet(ctx, i)
 	}
 
-	r, err := t.statements[add].Exec(vars...)
+	key := t.GetID(i)
+
+	old, err := s.snapshot(ctx, tx, t, key)
 	if err != nil {
 		return err
 	}
 
-	lid, err := r.LastInsertId()
-	if err != nil {
+	switch mode {
+	case setInsert:
+		if found, err := s.recordExists(ctx, tx, t, key); err != nil {
+			return err
+		} else if found {
+			return ErrExists
+		}
+
+		if err := s.insertKey(ctx, tx, i, t, key, vars, now); err != nil {
+			return err
+		}
+	case setUpdate:
+		if updated, err := s.update(ctx, tx, t, key, t.updateArgs(vars, key, version)); err != nil {
+			return s.uniqueError(typeName(i), t, err)
+		} else if !updated {
+			return ErrNotFound
+		} else if err = s.changed(ctx, tx, t, Updated, key, old); err != nil {
+			return err
+		}
+	default:
+		if t.statements[upsert] != nil && t.history == nil {
+			op, err := s.upsertOperation(ctx, tx, t, key)
+			if err != nil {
+				return err
+			}
+
+			if _, err := stmt(tx, t.statements[upsert]).ExecContext(ctx, append(key, vars...)...); err != nil {
+				return s.uniqueError(typeName(i), t, err)
+			} else if err = s.changed(ctx, tx, t, op, key, nil); err != nil {
+				return err
+			}
+		} else if updated, err := s.update(ctx, tx, t, key, t.updateArgs(vars, key, version)); err != nil {
+			return s.uniqueError(typeName(i), t, err)
+		} else if !updated {
+			if deleted, err := s.softDeleted(ctx, tx, t, key); err != nil {
+				return err
+			} else if deleted {
+				return ErrNotFound
+			} else if err := s.insertKey(ctx, tx, i, t, key, vars, now); err != nil {
+				return err
+			}
+		} else if err = s.changed(ctx, tx, t, Updated, key, old); err != nil {
+			return err
+		}
+	}
+
+	t.setVersion(i, version+1)
+
+	if err := s.setChildren(ctx, tx, i, t, key[0], toSet); err != nil {
 		return err
 	}
 
-	t.SetID(i, lid)
+	return afterSet(ctx, i)
+}
 
-	return nil
+// insertKey inserts a record with the given key, setting its created fields.
+func (s *Store) insertKey(ctx context.Context, tx *sql.Tx, i interface{}, t *typeInfo, key, vars []interface{}, now time.Time) error {
+	if err := t.setCreated(i, vars, now); err != nil {
+		return err
+	}
+
+	if _, err := stmt(tx, t.statements[addKey]).ExecContext(ctx, append(key[:len(key):len(key)], vars...)...); err != nil {
+		return s.uniqueError(typeName(i), t, err)
+	}
+
+	return s.changed(ctx, tx, t, Inserted, key, nil)
+}
+
+func (s *Store) insert(ctx context.Context, tx *sql.Tx, t *typeInfo, vars []interface{}) (int64, error) {
+	if s.dialect.ReturningID() {
+		var id int64
+
+		err := stmt(tx, t.statements[add]).QueryRowContext(ctx, vars...).Scan(&id)
+
+		return id, err
+	}
+
+	r, err := stmt(tx, t.statements[add]).ExecContext(ctx, vars...)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.LastInsertId()
+}
+
+func (s *Store) update(ctx context.Context, tx *sql.Tx, t *typeInfo, key, args []interface{}) (bool, error) {
+	r, err := stmt(tx, t.statements[update]).ExecContext(ctx, args...)
+	if err != nil {
+		return false, err
+	}
+
+	if ra, err := r.RowsAffected(); err != nil {
+		return false, err
+	} else if ra > 0 {
+		return true, nil
+	}
+
+	// some databases only count changed rows, so check the record exists
+	found, err := s.recordExists(ctx, tx, t, key)
+	if found && t.hasRole(roleVersion) {
+		return false, ErrConflict
+	}
+
+	return found, err
+}
+
+func (s *Store) recordExists(ctx context.Context, tx *sql.Tx, t *typeInfo, key []interface{}) (bool, error) {
+	err := stmt(tx, t.statements[exists]).QueryRowContext(ctx, key...).Scan(new(int))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
 }
 
 func (s *Store) Get(is ...interface{}) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.GetContext(context.Background(), is...)
+}
+
+func (s *Store) GetContext(ctx context.Context, is ...interface{}) error {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
 
-	return s.get(is...)
+	return s.get(ctx, nil, loadOptions{depth: -1}, is...)
 }
 
-func (s *Store) get(is ...interface{}) error {
+func (s *Store) get(ctx context.Context, tx *sql.Tx, opts loadOptions, is ...interface{}) error {
+	var (
+		names  []string
+		groups = make(map[string][]interface{})
+		loaded []interface{}
+		toGet  []interface{}
+	)
+
 	for _, i := range is {
-		t, ok := s.types[typeName(i)]
-		if !ok {
+		name := typeName(i)
+		if _, ok := s.types[name]; !ok {
 			return ErrUnregisteredType
 		}
 
-		id := t.GetID(i)
-		if id == 0 {
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+
+		groups[name] = append(groups[name], i)
+	}
+
+	for _, name := range names {
+		t := s.types[name]
+
+		got, refs, err := s.getType(ctx, tx, name, &t, groups[name], opts.withDeleted)
+		if err != nil {
+			return err
+		}
+
+		related, err := s.getRelated(ctx, tx, &t, got, refs)
+		if err != nil {
+			return err
+		}
+
+		loaded = append(loaded, got...)
+		toGet = append(toGet, related...)
+	}
+
+	if len(toGet) > 0 && opts.depth != 0 {
+		if err := s.get(ctx, tx, opts.related(), toGet...); err != nil {
+			return err
+		}
+	}
+
+	return afterGet(ctx, loaded)
+}
+
+func (s *Store) getType(ctx context.Context, tx *sql.Tx, name string, t *typeInfo, is []interface{}, withDeleted bool) ([]interface{}, [][]*interface{}, error) {
+	var (
+		keys   [][]interface{}
+		byKey  = make(map[interface{}][]interface{})
+		loaded []interface{}
+		refs   [][]*interface{}
+		cached = tx == nil && t.cache != nil
+		gen    uint64
+	)
+
+	if cached {
+		gen = t.cache.generation()
+	}
+
+	for _, i := range is {
+		if !t.hasID(i) {
 			continue
 		}
 
-		vars := make([]interface{}, 0, len(t.fields)-1)
+		key := t.GetID(i)
+		mk := mapKey(key)
 
-		var toGet []interface{}
+		if cached {
+			if values, ok := t.cache.get(mk); ok {
+				ref, err := t.scanImage(i, key, values)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				loaded = append(loaded, i)
+				refs = append(refs, ref)
 
-		for pos, f := range t.fields {
-			if pos == t.primary {
 				continue
 			}
+		}
 
-			if f.isStruct {
-				ni := getFieldPointer(i, f.pos)
-				nt := s.types[typeName(ni)]
-				toGet = append(toGet, ni)
-				vars = append(vars, getFieldPointer(ni, nt.fields[nt.primary].pos))
-			} else {
-				vars = append(vars, getFieldPointer(i, f.pos))
+		if _, ok := byKey[mk]; !ok {
+			keys = append(keys, key)
+		}
+
+		byKey[mk] = append(byKey[mk], i)
+	}
+
+	found := reflect.New(t.typ).Interface()
+	discard := make([]interface{}, len(t.fields))
+
+	copy(discard, t.keyScanners(found))
+
+	for n := len(t.keys); n < len(discard); n++ {
+		discard[n] = new(interface{})
+	}
+
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > maxBatch {
+			batch = batch[:maxBatch]
+		}
+
+		keys = keys[len(batch):]
+
+		var (
+			rows *sql.Rows
+			err  error
+		)
+
+		if len(batch) == 1 && !withDeleted {
+			rows, err = stmt(tx, t.statements[get]).QueryContext(ctx, batch[0]...)
+		} else {
+			where := t.keyIn(len(batch))
+
+			if scope := t.scope(withDeleted); scope != "" {
+				where += " AND " + scope
 			}
+
+			rows, err = s.query(ctx, tx, "SELECT "+t.columns()+" FROM ["+name+"] WHERE "+where+";", flattenKeys(batch)...)
 		}
 
-		row := t.statements[get].QueryRow(id)
+		if err != nil {
+			return nil, nil, err
+		}
 
-		if err := row.Scan(vars...); err == sql.ErrNoRows {
-			t.SetID(i, 0)
-		} else if err != nil {
-			return err
-		} else if len(toGet) > 0 {
-			if err = s.get(toGet...); err != nil {
-				return err
+	Rows:
+		for rows.Next() {
+			if err = rows.Scan(discard...); err != nil {
+				break
+			}
+
+			mk := mapKey(t.GetID(found))
+
+			if cached && (!withDeleted || t.scope(false) == "") {
+				t.cache.put(mk, rawValues(discard[len(t.keys):]), gen)
+			}
+
+			for _, i := range byKey[mk] {
+				var ref []*interface{}
+
+				if ref, err = t.scanRow(rows, i); err != nil {
+					break Rows
+				}
+
+				loaded = append(loaded, i)
+				refs = append(refs, ref)
 			}
+
+			delete(byKey, mk)
+		}
+
+		rows.Close()
+
+		if err != nil {
+			return nil, nil, err
+		} else if err = rows.Err(); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	return nil
+	for _, is := range byKey {
+		for _, i := range is {
+			t.clearID(i)
+		}
+	}
+
+	return loaded, refs, nil
+}
+
+func (t *typeInfo) scanRow(rows *sql.Rows, i interface{}) ([]*interface{}, error) {
+	vars, refs := t.fieldScanners(i)
+
+	if err := rows.Scan(append(t.keyScanners(i), vars...)...); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// fieldScanners returns the scanners for the non-key columns of a record, and
+// the references scanned for its struct fields.
+func (t *typeInfo) fieldScanners(i interface{}) ([]interface{}, []*interface{}) {
+	var (
+		vars = make([]interface{}, 0, len(t.fields)-len(t.keys))
+		refs []*interface{}
+	)
+
+	for pos, f := range t.fields {
+		if t.isKey(pos) {
+			continue
+		}
+
+		if f.isStruct {
+			ref := new(interface{})
+			refs = append(refs, ref)
+			vars = append(vars, ref)
+		} else {
+			vars = append(vars, fieldScanner(i, f))
+		}
+	}
+
+	return vars, refs
+}
+
+func (s *Store) getRelated(ctx context.Context, tx *sql.Tx, t *typeInfo, is []interface{}, refs [][]*interface{}) ([]interface{}, error) {
+	if len(is) == 0 {
+		return nil, nil
+	}
+
+	var toGet []interface{}
+
+	for n, i := range is {
+		related, err := s.setRefs(i, t, refs[n])
+		if err != nil {
+			return nil, err
+		}
+
+		toGet = append(toGet, related...)
+	}
+
+	children, err := s.getChildren(ctx, tx, t, is)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(toGet, children...), nil
+}
+
+func (s *Store) query(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (*sql.Rows, error) {
+	query = rebind(s.dialect, query)
+
+	if tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+
+	return s.db.QueryContext(ctx, query, args...)
+}
+
+func (s *Store) setRefs(i interface{}, t *typeInfo, refs []*interface{}) ([]interface{}, error) {
+	var toGet []interface{}
+
+	for pos, f := range t.fields {
+		if t.isKey(pos) || !f.isStruct {
+			continue
+		}
+
+		ref := refs[0]
+		refs = refs[1:]
+		v := reflect.ValueOf(i).Elem().Field(f.pos)
+
+		if *ref == nil {
+			v.Set(reflect.Zero(v.Type()))
+
+			continue
+		}
+
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+		} else {
+			v = v.Addr()
+		}
+
+		ni := v.Interface()
+		nt := s.types[typeName(ni)]
+
+		if err := nt.SetID(ni, []interface{}{*ref}); err != nil {
+			return nil, err
+		}
+
+		if !f.lazy {
+			toGet = append(toGet, ni)
+		}
+	}
+
+	return toGet, nil
 }
 
 func (s *Store) GetPage(is []interface{}, offset int) (int, error) {
+	return s.GetPageContext(context.Background(), is, offset)
+}
+
+func (s *Store) GetPageContext(ctx context.Context, is []interface{}, offset int) (int, error) {
 	if len(is) == 0 {
 		return 0, nil
 	}
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
 
+	return s.page(ctx, nil, is, offset)
+}
+
+func (s *Store) page(ctx context.Context, tx *sql.Tx, is []interface{}, offset int) (int, error) {
 	t, ok := s.types[typeName(is[0])]
 	if !ok {
 		return 0, ErrInvalidType
 	}
 
-	rows, err := t.statements[getPage].Query(len(is), offset)
+	rows, err := stmt(tx, t.statements[getPage]).QueryContext(ctx, len(is), offset)
 	if err != nil {
 		return 0, err
 	}
 
 	defer rows.Close()
 
-	return s.getPage(is, rows)
+	return s.getPage(ctx, tx, is, rows)
 }
 
-func (s *Store) getPage(is []interface{}, rows *sql.Rows) (int, error) {
+func (s *Store) getPage(ctx context.Context, tx *sql.Tx, is []interface{}, rows *sql.Rows) (int, error) {
 	t := s.types[typeName(is[0])]
 	n := 0
+	refs := make([][]*interface{}, 0, len(is))
 
-	for rows.Next() {
-		var id int64
-
-		if err := rows.Scan(&id); err != nil {
+	for n < len(is) && rows.Next() {
+		ref, err := t.scanRow(rows, is[n])
+		if err != nil {
 			return 0, err
 		}
 
-		t.SetID(is[n], id)
+		refs = append(refs, ref)
 
 		n++
 	}
@@ -464,36 +1284,125 @@ func (s *Store) getPage(is []interface{}, rows *sql.Rows) (int, error) {
 		return 0, nil
 	} else if err != nil {
 		return 0, err
-	} else if err = s.get(is...); err != nil {
+	}
+
+	rows.Close()
+
+	toGet, err := s.getRelated(ctx, tx, &t, is, refs)
+	if err != nil {
+		return 0, err
+	} else if len(toGet) > 0 {
+		if err = s.get(ctx, tx, loadOptions{depth: -1}.related(), toGet...); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = afterGet(ctx, is); err != nil {
 		return 0, err
 	}
 
 	return n, nil
 }
 
+// Remove removes the given records, along with their children and the records
+// they own via cascade delete policies.
+//
+// Records of types with a soft delete field are not removed, and instead have
+// the field set to the current time; see Purge.
 func (s *Store) Remove(is ...interface{}) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.RemoveContext(context.Background(), is...)
+}
+
+func (s *Store) RemoveContext(ctx context.Context, is ...interface{}) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.remove(ctx, tx, is, false)
+	})
+}
 
+func (s *Store) remove(ctx context.Context, tx *sql.Tx, is []interface{}, purge bool) error {
 	for _, i := range is {
 		t, ok := s.types[typeName(i)]
 		if !ok {
 			return ErrUnregisteredType
 		}
 
-		_, err := t.statements[remove].Exec(t.GetID(i))
+		if err := beforeRemove(ctx, i); err != nil {
+			return err
+		}
+
+		key := t.GetID(i)
+
+		old, err := s.snapshot(ctx, tx, &t, key)
 		if err != nil {
 			return err
 		}
+
+		if t.statements[softRemove] != nil && !purge {
+			if removed, err := s.softRemove(ctx, tx, i, &t, key); err != nil {
+				return err
+			} else if removed {
+				if err := s.changed(ctx, tx, &t, SoftDeleted, key, old); err != nil {
+					return err
+				}
+			}
+
+			if err := afterRemove(ctx, i); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := s.removeReferences(ctx, tx, typeName(i), key[0]); err != nil {
+			return err
+		}
+
+		cascade, err := s.cascades(ctx, tx, typeName(i), &t, key)
+		if err != nil {
+			return err
+		}
+
+		if err := s.removeChildren(ctx, tx, &t, key[0]); err != nil {
+			return err
+		}
+
+		r, err := stmt(tx, t.statements[remove]).ExecContext(ctx, key...)
+		if err != nil {
+			return err
+		}
+
+		if ra, err := r.RowsAffected(); err != nil {
+			return err
+		} else if ra > 0 {
+			if err := s.changed(ctx, tx, &t, Deleted, key, old); err != nil {
+				return err
+			}
+		}
+
+		if err := s.remove(ctx, tx, cascade, purge); err != nil {
+			return err
+		}
+
+		if err := afterRemove(ctx, i); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
 func (s *Store) Count(i interface{}) (int, error) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	return s.CountContext(context.Background(), i)
+}
+
+func (s *Store) CountContext(ctx context.Context, i interface{}) (int, error) {
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	return s.count(ctx, nil, i)
+}
 
+func (s *Store) count(ctx context.Context, tx *sql.Tx, i interface{}) (int, error) {
 	if !isPointerStruct(i) {
 		return 0, ErrNoPointerStruct
 	}
@@ -504,17 +1413,29 @@ func (s *Store) Count(i interface{}) (int, error) {
 	}
 
 	num := 0
-	err := t.statements[count].QueryRow().Scan(&num)
+	err := stmt(tx, t.statements[count]).QueryRowContext(ctx).Scan(&num)
 
 	return num, err
 }
 
 // Errors.
 var (
-	ErrDBClosed         = errors.New("database already closed")
-	ErrNoPointerStruct  = errors.New("given variable is not a pointer to a struct")
-	ErrNoKey            = errors.New("could not determine key")
-	ErrDuplicateColumn  = errors.New("duplicate column name found")
-	ErrUnregisteredType = errors.New("type not registered")
-	ErrInvalidType      = errors.New("invalid type")
+	ErrDBClosed           = errors.New("database already closed")
+	ErrNoPointerStruct    = errors.New("given variable is not a pointer to a struct")
+	ErrNoKey              = errors.New("could not determine key")
+	ErrDuplicateColumn    = errors.New("duplicate column name found")
+	ErrUnregisteredType   = errors.New("type not registered")
+	ErrInvalidType        = errors.New("invalid type")
+	ErrUnknownColumn      = errors.New("unknown column")
+	ErrNotFound           = errors.New("record not found")
+	ErrIncompatibleColumn = errors.New("incompatible column")
+	ErrInvalidTag         = errors.New("invalid struct tag value")
+	ErrReferenced         = errors.New("record is still referenced")
+	ErrTxDone             = errors.New("transaction already committed or rolled back")
+	ErrExists             = errors.New("record already exists")
+	ErrCompositeKey       = errors.New("types with composite keys cannot be referenced or contain slices")
+	ErrUniqueViolation    = errors.New("unique constraint violated")
+	ErrConflict           = errors.New("record was changed by another writer")
+	ErrNotAudited         = errors.New("type is not audited")
+	ErrMapField           = errors.New("map fields cannot be stored")
 )
@@ -1,8 +1,13 @@
 package store
 
 import (
+	"context"
+	"database/sql/driver"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newTestStore() (*Store, error) {
@@ -15,6 +20,26 @@ type testType struct {
 	Number int64
 }
 
+func TestInMemory(t *testing.T) {
+	for n, test := range [...]struct {
+		dsn      string
+		expected bool
+	}{
+		{"", true},
+		{":memory:", true},
+		{"file::memory:", true},
+		{"file::memory:?cache=shared", true},
+		{"file:data?mode=memory&cache=shared", true},
+		{"file:data.db?mode=rwc", false},
+		{"data.db", false},
+		{"memory.db", false},
+	} {
+		if got := inMemory(test.dsn); got != test.expected {
+			t.Errorf("test %d: expecting %v for %q, got %v", n+1, test.expected, test.dsn, got)
+		}
+	}
+}
+
 func TestSetGet(t *testing.T) {
 	s, err := newTestStore()
 	defer s.Close()
@@ -216,3 +241,315 @@ func TestGetPage(t *testing.T) {
 		}
 	}
 }
+
+func TestContext(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tt := testType{0, "HELLO", 1}
+	if err = s.SetContext(context.Background(), &tt); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = s.GetContext(ctx, &testType{ID: tt.ID}); err != context.DeadlineExceeded {
+		t.Errorf("test 3: expecting error %s, got %v", context.DeadlineExceeded, err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("test 4: received unexpected error: %s", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err = s.CountContext(ctx, new(testType)); err != context.Canceled {
+		t.Errorf("test 5: expecting error %s, got %v", context.Canceled, err)
+	}
+	got := testType{ID: tt.ID}
+	if err = s.GetContext(context.Background(), &got); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if got != tt {
+		t.Errorf("test 6: expecting %v, got %v", tt, got)
+	}
+}
+
+type (
+	namedInt    int16
+	namedString string
+	namedBytes  []byte
+	namedBool   bool
+)
+
+type scalarTypes struct {
+	Key     uint32 `key:"1"`
+	Bool    bool
+	Int8    int8
+	Int16   int16
+	Int32   int32
+	Uint    uint
+	Uint8   uint8
+	Uint16  uint16
+	Uint64  uint64
+	Float32 float32
+	Bytes   []byte
+	NInt    namedInt
+	NString namedString
+	NBytes  namedBytes
+	NBool   namedBool
+}
+
+func TestScalarTypes(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(scalarTypes)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if typ := s.types[typeName(new(scalarTypes))]; len(typ.fields) != 15 {
+		t.Fatalf("expecting 15 fields, got %d", len(typ.fields))
+	} else if typ.keyField(0).name != "Key" {
+		t.Fatalf("expecting key field Key, got %s", typ.keyField(0).name)
+	}
+	st := scalarTypes{0, true, -8, -16, -32, 1, 8, 16, 64, 1.5, []byte("bytes"), -3, "named", namedBytes("nbytes"), true}
+	if err = s.Set(&st); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if st.Key != 1 {
+		t.Fatalf("test 1: expecting key 1, got %d", st.Key)
+	}
+	got := scalarTypes{Key: st.Key}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if !reflect.DeepEqual(got, st) {
+		t.Errorf("test 2: expecting %v, got %v", st, got)
+	}
+	search := s.NewSearch(new(scalarTypes))
+	search.Filter = And{Eq("NInt", namedInt(-3)), Eq("Bool", true), Eq("Float32", float32(1.5))}
+	ps, err := search.Prepare()
+	if err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	}
+	if n, err := ps.Count(); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if n != 1 {
+		t.Errorf("test 3: expecting count 1, got %d", n)
+	}
+}
+
+type upper string
+
+func (u upper) Value() (driver.Value, error) {
+	return strings.ToUpper(string(u)), nil
+}
+
+func (u *upper) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		*u = upper(strings.ToLower(src))
+	case []byte:
+		*u = upper(strings.ToLower(string(src)))
+	default:
+		return ErrInvalidType
+	}
+	return nil
+}
+
+type point struct {
+	X, Y int
+}
+
+func (p point) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(p.X) + "," + strconv.Itoa(p.Y)), nil
+}
+
+func (p *point) UnmarshalText(text []byte) error {
+	x, y, _ := strings.Cut(string(text), ",")
+	var err error
+	if p.X, err = strconv.Atoi(x); err != nil {
+		return err
+	}
+	p.Y, err = strconv.Atoi(y)
+	return err
+}
+
+type celsius struct {
+	n int64
+}
+
+func (c celsius) Value() (driver.Value, error) {
+	return c.n, nil
+}
+
+func (c *celsius) Scan(src interface{}) error {
+	n, ok := src.(int64)
+	if !ok {
+		return ErrInvalidType
+	}
+	c.n = n
+	return nil
+}
+
+func (celsius) ColumnType() string {
+	return "INTEGER"
+}
+
+type customTypes struct {
+	ID    int
+	Name  upper `type:"VARCHAR(32)"`
+	Point point
+	Temp  celsius
+}
+
+func TestCustomTypes(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(customTypes)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	columns, err := s.dialect.Columns(s.db, typeName(new(customTypes)))
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	expected := []Column{{"ID", "INTEGER"}, {"Name", "VARCHAR(32)"}, {"Point", "TEXT"}, {"Temp", "INTEGER"}}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expecting columns %v, got %v", expected, columns)
+	}
+	ct := customTypes{0, "hello", point{3, -4}, celsius{21}}
+	if err = s.Set(&ct); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	got := customTypes{ID: ct.ID}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if got != ct {
+		t.Errorf("test 2: expecting %v, got %v", ct, got)
+	}
+	search := s.NewSearch(new(customTypes))
+	search.Filter = And{Eq("Name", upper("hello")), Eq("Point", point{3, -4}), Eq("Temp", celsius{21})}
+	ps, err := search.Prepare()
+	if err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	}
+	if n, err := ps.Count(); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if n != 1 {
+		t.Errorf("test 3: expecting count 1, got %d", n)
+	}
+}
+
+func benchmarkStore(b *testing.B, n int) *Store {
+	s, err := newTestStore()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err = s.Register(new(embeddedTestType)); err != nil {
+		b.Fatal(err)
+	}
+	is := make([]interface{}, n)
+	for i := range is {
+		is[i] = &embeddedTestType{0, "Beep", testType{0, "Boop", int64(i)}}
+	}
+	if err = s.Set(is...); err != nil {
+		b.Fatal(err)
+	}
+	return s
+}
+
+func BenchmarkGetPage(b *testing.B) {
+	s := benchmarkStore(b, 100)
+	defer s.Close()
+	data := make([]embeddedTestType, 100)
+	is := make([]interface{}, 100)
+	for i := range is {
+		is[i] = &data[i]
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if _, err := s.GetPage(is, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetEach(b *testing.B) {
+	s := benchmarkStore(b, 100)
+	defer s.Close()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for id := 1; id <= 100; id++ {
+			if err := s.Get(&embeddedTestType{ID: id}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSetModes(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType), new(embeddedTestType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	for n, test := range []struct {
+		set    func(...interface{}) error
+		value  testType
+		err    error
+		result testType
+	}{
+		{s.Insert, testType{0, "One", 1}, nil, testType{1, "One", 1}},
+		{s.Insert, testType{1, "Two", 2}, ErrExists, testType{1, "One", 1}},
+		{s.Insert, testType{5, "Five", 5}, nil, testType{5, "Five", 5}},
+		{s.Update, testType{5, "Six", 6}, nil, testType{5, "Six", 6}},
+		{s.Update, testType{5, "Six", 6}, nil, testType{5, "Six", 6}},
+		{s.Update, testType{7, "Seven", 7}, ErrNotFound, testType{}},
+		{s.Update, testType{0, "Zero", 0}, ErrNotFound, testType{}},
+		{s.Upsert, testType{7, "Seven", 7}, nil, testType{7, "Seven", 7}},
+		{s.Upsert, testType{7, "Eight", 8}, nil, testType{7, "Eight", 8}},
+		{s.Upsert, testType{0, "Nine", 9}, nil, testType{8, "Nine", 9}},
+		{s.Set, testType{12, "Twelve", 12}, nil, testType{12, "Twelve", 12}},
+	} {
+		value := test.value
+		if err := test.set(&value); err != test.err {
+			t.Errorf("test %d: expecting error %v, got %v", n+1, test.err, err)
+		} else if err != nil {
+			if test.result.ID == 0 {
+				continue
+			}
+			value = test.result
+		}
+		got := testType{ID: value.ID}
+		if err := s.Get(&got); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if got != test.result {
+			t.Errorf("test %d: expecting %v, got %v", n+1, test.result, got)
+		}
+	}
+	if n, err := s.Count(new(testType)); err != nil {
+		t.Errorf("received unexpected error: %s", err)
+	} else if n != 5 {
+		t.Errorf("expecting count 5, got %d", n)
+	}
+	if err = s.Insert(&embeddedTestType{0, "Nested", testType{1, "New One", 1}}); err != nil {
+		t.Errorf("received unexpected error: %s", err)
+	}
+	got := testType{ID: 1}
+	if err = s.Get(&got); err != nil {
+		t.Errorf("received unexpected error: %s", err)
+	} else if expected := (testType{1, "New One", 1}); got != expected {
+		t.Errorf("expecting nested record %v, got %v", expected, got)
+	}
+}
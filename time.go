@@ -0,0 +1,163 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TimeEncoding determines how time.Time and sql.NullTime values are stored.
+type TimeEncoding uint8
+
+// Time encodings.
+//
+// Times stored as unix timestamps are retrieved in UTC. The zero time, which
+// is outside of the range of nanosecond timestamps, is stored as NULL when
+// using nanoseconds, and so is matched in filters with IsNull instead of Eq. Times stored as RFC 3339 text are
+// converted to UTC and always given nine fractional digits, so that the text
+// sorts in time order, and so are also retrieved in UTC.
+const (
+	TimeUnix TimeEncoding = iota
+	TimeUnixNano
+	TimeRFC3339
+)
+
+// rfc3339Fixed is the fixed width form of RFC 3339 used to store times.
+const rfc3339Fixed = "2006-01-02T15:04:05.000000000Z07:00"
+
+func parseTimeEncoding(name string) (TimeEncoding, bool) {
+	switch name {
+	case "unix":
+		return TimeUnix, true
+	case "unixnano":
+		return TimeUnixNano, true
+	case "rfc3339":
+		return TimeRFC3339, true
+	}
+
+	return 0, false
+}
+
+// SetTimeEncoding sets the encoding of time.Time values for types registered
+// afterwards, and for time.Time values used in filters that are not compared
+// with a column of a known encoding. Individual fields can
+// select an encoding with the time option of the store tag, i.e.
+// `store:"name,time=rfc3339"`, with the values unix, unixnano and rfc3339.
+//
+// The default encoding is TimeUnix.
+func (s *Store) SetTimeEncoding(e TimeEncoding) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.typesMutex.Lock()
+	defer s.typesMutex.Unlock()
+
+	s.timeEncoding = e
+}
+
+func isTimeEncoding(enc uint8) bool {
+	return enc >= encodeUnix
+}
+
+// fieldEncoding returns the encoding for a field with the given tag options,
+// applying the time encoding to time fields.
+func (s *Store) fieldEncoding(enc uint8, opts []string) (uint8, error) {
+	if !isTimeEncoding(enc) {
+		if optionValue(opts, "time") != "" {
+			return 0, ErrInvalidTag
+		}
+
+		return enc, nil
+	}
+
+	e := s.timeEncoding
+
+	if name := optionValue(opts, "time"); name != "" {
+		var ok bool
+
+		if e, ok = parseTimeEncoding(name); !ok {
+			return 0, ErrInvalidTag
+		}
+	}
+
+	return encodeUnix + uint8(e), nil
+}
+
+func timeSQLType(enc uint8) string {
+	if enc == encodeRFC3339 {
+		return "TEXT"
+	}
+
+	return "INTEGER"
+}
+
+func encodeTime(t time.Time, enc uint8) interface{} {
+	switch enc {
+	case encodeUnixNano:
+		if t.IsZero() {
+			return nil
+		}
+
+		return t.UnixNano()
+	case encodeRFC3339:
+		return t.UTC().Format(rfc3339Fixed)
+	}
+
+	return t.Unix()
+}
+
+type timeScanner struct {
+	*time.Time
+	encoding uint8
+}
+
+func (t timeScanner) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		*t.Time = time.Time{}
+	case int64:
+		switch t.encoding {
+		case encodeUnix:
+			*t.Time = time.Unix(src, 0).UTC()
+		case encodeUnixNano:
+			*t.Time = time.Unix(0, src).UTC()
+		default:
+			return ErrInvalidType
+		}
+	case string:
+		return t.parse(src)
+	case []byte:
+		return t.parse(string(src))
+	case time.Time:
+		*t.Time = src
+	default:
+		return ErrInvalidType
+	}
+
+	return nil
+}
+
+type nullTimeScanner struct {
+	*sql.NullTime
+	encoding uint8
+}
+
+func (n nullTimeScanner) Scan(src interface{}) error {
+	n.Valid = src != nil
+
+	return timeScanner{&n.Time, n.encoding}.Scan(src)
+}
+
+func (t timeScanner) parse(src string) error {
+	if t.encoding != encodeRFC3339 {
+		return ErrInvalidType
+	}
+
+	tm, err := time.Parse(time.RFC3339Nano, src)
+	if err != nil {
+		return err
+	}
+
+	*t.Time = tm
+
+	return nil
+}
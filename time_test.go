@@ -0,0 +1,181 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type timeRecord struct {
+	ID      int
+	Default time.Time
+	Unix    time.Time  `store:"unix,time=unix"`
+	Nano    time.Time  `store:"nano,time=unixnano"`
+	Text    *time.Time `store:"text,time=rfc3339"`
+	Times   []time.Time
+}
+
+type badTimeTag struct {
+	ID   int
+	Name string `store:"name,time=unix"`
+}
+
+type badTimeEncoding struct {
+	ID   int
+	When time.Time `store:"when,time=julian"`
+}
+
+func TestTimeEncoding(t *testing.T) {
+	zone := time.FixedZone("UTC+5:30", 5*3600+1800)
+	when := time.Date(2024, 2, 29, 13, 14, 15, 123456789, zone)
+	for n, test := range []struct {
+		encoding TimeEncoding
+		columns  map[string]string
+		def      time.Time
+	}{
+		{TimeUnix, map[string]string{"Default": "integer", "unix": "integer", "nano": "integer", "text": "text"}, when.Truncate(time.Second)},
+		{TimeUnixNano, map[string]string{"Default": "integer", "unix": "integer", "nano": "integer", "text": "text"}, when},
+		{TimeRFC3339, map[string]string{"Default": "text", "unix": "integer", "nano": "integer", "text": "text"}, when},
+	} {
+		s, err := newTestStore()
+		if err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		s.SetTimeEncoding(test.encoding)
+		if err = s.Register(new(timeRecord)); err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		text := when
+		tt := timeRecord{Default: when, Unix: when, Nano: when, Text: &text, Times: []time.Time{when, {}}}
+		if err = s.Set(&tt, &timeRecord{}); err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		for column, expected := range test.columns {
+			var typ string
+			if err = s.db.QueryRow("SELECT typeof([" + column + "]) FROM [store.timeRecord] WHERE [ID] = 1;").Scan(&typ); err != nil {
+				t.Errorf("test %d: received unexpected error: %s", n+1, err)
+			} else if typ != expected {
+				t.Errorf("test %d: expecting column %s to be %s, got %s", n+1, column, expected, typ)
+			}
+		}
+		got := timeRecord{ID: tt.ID}
+		if err = s.Get(&got); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if !got.Default.Equal(test.def) {
+			t.Errorf("test %d: expecting default time %s, got %s", n+1, test.def, got.Default)
+		} else if !got.Unix.Equal(when.Truncate(time.Second)) || got.Unix.Location() != time.UTC {
+			t.Errorf("test %d: expecting unix time %s in UTC, got %s", n+1, when.Truncate(time.Second), got.Unix)
+		} else if !got.Nano.Equal(when) || got.Nano.Location() != time.UTC {
+			t.Errorf("test %d: expecting unixnano time %s in UTC, got %s", n+1, when, got.Nano)
+		} else if got.Text == nil || !got.Text.Equal(when) || got.Text.Location() != time.UTC {
+			t.Errorf("test %d: expecting text time %s in UTC, got %v", n+1, when, got.Text)
+		} else if len(got.Times) != 2 || !got.Times[0].Equal(test.def) || !got.Times[1].IsZero() {
+			t.Errorf("test %d: expecting times [%s, zero], got %v", n+1, test.def, got.Times)
+		}
+		empty := timeRecord{ID: 2}
+		if err = s.Get(&empty); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if !empty.Default.IsZero() || !empty.Unix.IsZero() || !empty.Nano.IsZero() || empty.Text != nil {
+			t.Errorf("test %d: expecting zero times, got %v", n+1, empty)
+		}
+		epoch := timeRecord{Nano: time.Unix(0, 0)}
+		if err = s.Set(&epoch); err != nil {
+			t.Errorf("test %d: received unexpected error: %s", n+1, err)
+		} else if got := (timeRecord{ID: epoch.ID}); s.Get(&got) != nil || !got.Nano.Equal(epoch.Nano) || got.Nano.IsZero() {
+			t.Errorf("test %d: expecting unixnano time %s, got %s", n+1, epoch.Nano, got.Nano)
+		}
+		for _, filter := range []Filter{
+			Eq("Default", test.def),
+			Eq("text", when),
+			And{Eq("nano", when), Not(In("unix", time.Time{}, when.Add(time.Hour)))},
+			Between("unix", when.Add(-time.Second), when),
+			IsNull("nano"),
+		} {
+			search := s.NewSearch(new(timeRecord))
+			search.Filter = filter
+			if p, err := search.Prepare(); err != nil {
+				t.Errorf("test %d: received unexpected error: %s", n+1, err)
+			} else if c, err := p.Count(); err != nil {
+				t.Errorf("test %d: received unexpected error: %s", n+1, err)
+			} else if c != 1 {
+				t.Errorf("test %d: expecting filter %s to match 1 record, got %d", n+1, filter.SQL(), c)
+			}
+		}
+		s.Close()
+	}
+}
+
+type textTime struct {
+	ID   int
+	When time.Time `store:"when,time=rfc3339"`
+}
+
+func TestTimeRFC3339Order(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(textTime)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	records := []textTime{
+		{When: base.Add(150 * time.Millisecond)},
+		{When: base},
+		{When: base.Add(100 * time.Millisecond)},
+		{When: base.Add(-time.Hour).In(time.FixedZone("UTC+2", 7200))},
+	}
+	for n := range records {
+		if err = s.Set(&records[n]); err != nil {
+			t.Fatalf("received unexpected error: %s", err)
+		}
+	}
+	search := s.NewSearch(new(textTime))
+	search.Filter = Gt("when", base.Add(100*time.Millisecond))
+	if p, err := search.Prepare(); err != nil {
+		t.Errorf("test 1: received unexpected error: %s", err)
+	} else if c, err := p.Count(); err != nil {
+		t.Errorf("test 1: received unexpected error: %s", err)
+	} else if c != 1 {
+		t.Errorf("test 1: expecting 1 record, got %d", c)
+	}
+	search = s.NewSearch(new(textTime))
+	search.Sort = []SortBy{{"when", true}}
+	p, err := search.Prepare()
+	if err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	defer p.Close()
+	cur, err := p.Cursor()
+	if err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	defer cur.Close()
+	var ids []int
+	for cur.Next() {
+		var tt textTime
+		if err = cur.Scan(&tt); err != nil {
+			t.Fatalf("test 2: received unexpected error: %s", err)
+		}
+		ids = append(ids, tt.ID)
+	}
+	if err = cur.Err(); err != nil {
+		t.Errorf("test 2: received unexpected error: %s", err)
+	} else if expected := []int{4, 2, 3, 1}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("test 2: expecting order %v, got %v", expected, ids)
+	}
+}
+
+func TestTimeEncodingErrors(t *testing.T) {
+	for n, i := range []interface{}{new(badTimeTag), new(badTimeEncoding)} {
+		s, err := newTestStore()
+		if err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+1, err)
+		}
+		if err = s.Register(i); err != ErrInvalidTag {
+			t.Errorf("test %d: expecting error %s, got %v", n+1, ErrInvalidTag, err)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,297 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+// Tx is a database transaction through which records can be atomically set,
+// retrieved and removed.
+//
+// Writes to the Store are locked out for the lifetime of the transaction, so
+// any calls to Set, Remove, Register or Begin on the Store will block until
+// either Commit or Rollback is called. Reads from a file database continue
+// concurrently, only seeing data that has been committed.
+type Tx struct {
+	store *Store
+	tx    *sql.Tx
+}
+
+func (s *Store) Begin() (*Tx, error) {
+	return s.BeginContext(context.Background())
+}
+
+// BeginContext starts a transaction, waiting on the Store lock until it is
+// acquired or the context is cancelled.
+//
+// The context is used for the lifetime of the transaction, so if it is
+// cancelled the transaction will be rolled back, though Commit or Rollback
+// must still be called to release the Store.
+func (s *Store) BeginContext(ctx context.Context) (*Tx, error) {
+	if err := s.mutex.LockContext(ctx); err != nil {
+		return nil, err
+	}
+
+	s.typesMutex.RLock()
+	defer s.typesMutex.RUnlock()
+
+	if s.db == nil {
+		s.mutex.Unlock()
+
+		return nil, ErrDBClosed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.mutex.Unlock()
+
+		return nil, err
+	}
+
+	return &Tx{
+		store: s,
+		tx:    tx,
+	}, nil
+}
+
+func (t *Tx) Commit() error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	err := t.tx.Commit()
+
+	t.store.finish(err == nil)
+	t.done()
+
+	return err
+}
+
+func (t *Tx) Rollback() error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	err := t.tx.Rollback()
+
+	t.store.finish(false)
+	t.done()
+
+	return err
+}
+
+// savedField is the value of a field of a record before it was changed during
+// a transaction.
+type savedField struct {
+	field, value reflect.Value
+}
+
+// save records the value of a field of a record before it is changed, so that
+// it can be restored if the transaction is rolled back.
+func (s *Store) save(i interface{}, pos int) {
+	v := reflect.ValueOf(i).Elem().Field(pos)
+	old := reflect.New(v.Type()).Elem()
+
+	old.Set(v)
+
+	s.saved = append(s.saved, savedField{v, old})
+}
+
+// finish publishes the events of a committed transaction, or restores the
+// fields of records changed by a transaction that was rolled back.
+func (s *Store) finish(committed bool) {
+	if !committed {
+		for n := len(s.saved) - 1; n >= 0; n-- {
+			s.saved[n].field.Set(s.saved[n].value)
+		}
+	}
+
+	s.saved = nil
+
+	s.publish(committed)
+}
+
+func (t *Tx) done() {
+	t.tx = nil
+
+	t.store.mutex.Unlock()
+}
+
+func (t *Tx) Set(is ...interface{}) error {
+	return t.SetContext(context.Background(), is...)
+}
+
+func (t *Tx) SetContext(ctx context.Context, is ...interface{}) error {
+	return t.set(ctx, is, setUpsert)
+}
+
+func (t *Tx) Insert(is ...interface{}) error {
+	return t.InsertContext(context.Background(), is...)
+}
+
+func (t *Tx) InsertContext(ctx context.Context, is ...interface{}) error {
+	return t.set(ctx, is, setInsert)
+}
+
+func (t *Tx) Update(is ...interface{}) error {
+	return t.UpdateContext(context.Background(), is...)
+}
+
+func (t *Tx) UpdateContext(ctx context.Context, is ...interface{}) error {
+	return t.set(ctx, is, setUpdate)
+}
+
+func (t *Tx) Upsert(is ...interface{}) error {
+	return t.UpsertContext(context.Background(), is...)
+}
+
+func (t *Tx) UpsertContext(ctx context.Context, is ...interface{}) error {
+	return t.set(ctx, is, setUpsert)
+}
+
+func (t *Tx) set(ctx context.Context, is []interface{}, mode uint8) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.setAll(ctx, t.tx, is, mode)
+}
+
+func (t *Tx) Get(is ...interface{}) error {
+	return t.GetContext(context.Background(), is...)
+}
+
+func (t *Tx) GetContext(ctx context.Context, is ...interface{}) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.get(ctx, t.tx, loadOptions{depth: -1}, is...)
+}
+
+func (t *Tx) GetPage(is []interface{}, offset int) (int, error) {
+	return t.GetPageContext(context.Background(), is, offset)
+}
+
+func (t *Tx) GetPageContext(ctx context.Context, is []interface{}, offset int) (int, error) {
+	if len(is) == 0 {
+		return 0, nil
+	}
+
+	if t.tx == nil {
+		return 0, ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.page(ctx, t.tx, is, offset)
+}
+
+func (t *Tx) Remove(is ...interface{}) error {
+	return t.RemoveContext(context.Background(), is...)
+}
+
+func (t *Tx) RemoveContext(ctx context.Context, is ...interface{}) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.remove(ctx, t.tx, is, false)
+}
+
+func (t *Tx) Purge(is ...interface{}) error {
+	return t.PurgeContext(context.Background(), is...)
+}
+
+func (t *Tx) PurgeContext(ctx context.Context, is ...interface{}) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.remove(ctx, t.tx, is, true)
+}
+
+func (t *Tx) Restore(is ...interface{}) error {
+	return t.RestoreContext(context.Background(), is...)
+}
+
+func (t *Tx) RestoreContext(ctx context.Context, is ...interface{}) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.restore(ctx, t.tx, is)
+}
+
+func (t *Tx) Count(i interface{}) (int, error) {
+	return t.CountContext(context.Background(), i)
+}
+
+func (t *Tx) CountContext(ctx context.Context, i interface{}) (int, error) {
+	if t.tx == nil {
+		return 0, ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.count(ctx, t.tx, i)
+}
+
+func (t *Tx) NewSearch(i interface{}) *Search {
+	if t.tx == nil {
+		return nil
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.newSearch(t.tx, i)
+}
+
+func (t *Tx) GetWith(i interface{}, opts ...LoadOption) error {
+	return t.GetWithContext(context.Background(), i, opts...)
+}
+
+func (t *Tx) GetWithContext(ctx context.Context, i interface{}, opts ...LoadOption) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.get(ctx, t.tx, getOptions(opts), i)
+}
+
+func (t *Tx) Load(i interface{}, field string, opts ...LoadOption) error {
+	return t.LoadContext(context.Background(), i, field, opts...)
+}
+
+func (t *Tx) LoadContext(ctx context.Context, i interface{}, field string, opts ...LoadOption) error {
+	if t.tx == nil {
+		return ErrTxDone
+	}
+
+	t.store.typesMutex.RLock()
+	defer t.store.typesMutex.RUnlock()
+
+	return t.store.load(ctx, t.tx, i, field, getOptions(opts))
+}
@@ -0,0 +1,107 @@
+package store
+
+import "testing"
+
+func TestTx(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType), new(embeddedTestType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = tx.Set(&testType{0, "HELLO", 1}, &testType{0, "WORLD", 2}); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	if c, err := tx.Count(new(testType)); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if c != 2 {
+		t.Errorf("test 2: expecting count 2, got %d", c)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	}
+	if err = tx.Set(&testType{}); err != ErrTxDone {
+		t.Errorf("test 4: expecting error %s, got %v", ErrTxDone, err)
+	}
+	if c, err := s.Count(new(testType)); err != nil {
+		t.Fatalf("test 5: received unexpected error: %s", err)
+	} else if c != 0 {
+		t.Errorf("test 5: expecting count 0, got %d", c)
+	}
+	if tx, err = s.Begin(); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	ett := embeddedTestType{0, "Beep", testType{0, "Boop", 3}}
+	if err = tx.Set(&ett); err != nil {
+		t.Fatalf("test 6: received unexpected error: %s", err)
+	}
+	search, err := tx.NewSearch(new(testType)).Prepare()
+	if err != nil {
+		t.Fatalf("test 7: received unexpected error: %s", err)
+	}
+	var tt testType
+	if n, err := search.GetPage([]interface{}{&tt}, 0); err != nil {
+		t.Fatalf("test 7: received unexpected error: %s", err)
+	} else if n != 1 || tt != ett.AnotherType {
+		t.Errorf("test 7: expecting %v, got %v", ett.AnotherType, tt)
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatalf("test 8: received unexpected error: %s", err)
+	}
+	got := embeddedTestType{ID: ett.ID}
+	if err = s.Get(&got); err != nil {
+		t.Fatalf("test 9: received unexpected error: %s", err)
+	} else if got != ett {
+		t.Errorf("test 9: expecting %v, got %v", ett, got)
+	}
+}
+
+func TestSetAtomic(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(testType)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	tt := testType{0, "HELLO", 1}
+	if err = s.Set(&tt, &struct{ ID int }{}); err != ErrUnregisteredType {
+		t.Errorf("test 1: expecting error %s, got %v", ErrUnregisteredType, err)
+	}
+	if c, err := s.Count(new(testType)); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if c != 0 {
+		t.Errorf("test 2: expecting count 0, got %d", c)
+	}
+	if tt.ID != 0 {
+		t.Errorf("test 3: expecting key to be reset, got %d", tt.ID)
+	}
+	if err = s.Set(&tt); err != nil {
+		t.Fatalf("test 4: received unexpected error: %s", err)
+	} else if err = s.Update(&tt); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if c, err := s.Count(new(testType)); err != nil || c != 1 {
+		t.Errorf("test 4: expecting count 1, got %d, %v", c, err)
+	}
+	tx, err := s.Begin()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	other := testType{0, "WORLD", 2}
+	if err = tx.Set(&other); err != nil {
+		t.Fatalf("test 5: received unexpected error: %s", err)
+	} else if other.ID == 0 {
+		t.Errorf("test 5: expecting key to be set")
+	} else if err = tx.Rollback(); err != nil {
+		t.Fatalf("test 5: received unexpected error: %s", err)
+	} else if other.ID != 0 {
+		t.Errorf("test 5: expecting key to be reset, got %d", other.ID)
+	}
+}
@@ -1,7 +1,12 @@
 package store
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
 	"reflect"
+	"strings"
 	"time"
 )
 
@@ -47,43 +52,117 @@ func getField(i interface{}, fieldNum int) interface{} {
 	return f.Interface()
 }
 
-func getType(i interface{}, fieldNum int) string {
-	v := getFieldPointer(i, fieldNum)
-	if v == nil {
-		return ""
+// ColumnTyper can be implemented by custom field types to declare the generic
+// type of the column they are stored in.
+type ColumnTyper interface {
+	ColumnType() string
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	nullTimeType        = reflect.TypeOf(sql.NullTime{})
+	valuerType          = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType         = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	columnTyperType     = reflect.TypeOf((*ColumnTyper)(nil)).Elem()
+	nullTypes           = map[reflect.Type]string{
+		reflect.TypeOf(sql.NullBool{}):    "BOOLEAN",
+		reflect.TypeOf(sql.NullByte{}):    "INTEGER",
+		reflect.TypeOf(sql.NullInt16{}):   "INTEGER",
+		reflect.TypeOf(sql.NullInt32{}):   "INTEGER",
+		reflect.TypeOf(sql.NullInt64{}):   "INTEGER",
+		reflect.TypeOf(sql.NullFloat64{}): "FLOAT",
+		reflect.TypeOf(sql.NullString{}):  "TEXT",
+		reflect.TypeOf(sql.NullTime{}):    "INTEGER",
+	}
+)
+
+const (
+	encodeNone uint8 = iota
+	encodeValuer
+	encodeText
+	encodeBytes
+	encodeUnix
+	encodeUnixNano
+	encodeRFC3339
+)
+
+func typeEncoding(t reflect.Type) uint8 {
+	if t == timeType || t == nullTimeType {
+		return encodeUnix
+	}
+
+	pt := reflect.PtrTo(t)
+
+	if pt.Implements(valuerType) && pt.Implements(scannerType) {
+		return encodeValuer
+	} else if pt.Implements(textMarshalerType) && pt.Implements(textUnmarshalerType) {
+		return encodeText
+	} else if t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 {
+		return encodeBytes
+	}
+
+	return encodeNone
+}
+
+func sqlType(t reflect.Type) string {
+	if t == timeType || t == nullTimeType {
+		return "INTEGER"
+	}
+
+	if enc := typeEncoding(t); enc != encodeNone && !isTimeEncoding(enc) {
+		if reflect.PtrTo(t).Implements(columnTyperType) {
+			return reflect.New(t).Interface().(ColumnTyper).ColumnType()
+		} else if typ, ok := nullTypes[t]; ok {
+			return typ
+		}
+
+		return "TEXT"
 	}
 
-	switch v.(type) {
-	case *int, *int64, *time.Time:
+	switch t.Kind() {
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		return "INTEGER"
-	case *float32, *float64:
+	case reflect.Float32, reflect.Float64:
 		return "FLOAT"
-	case *string:
+	case reflect.String:
 		return "TEXT"
-	case *[]byte:
-		return "BLOB"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB"
+		}
 	}
 
 	return ""
 }
 
-func isValidType(i interface{}) bool {
-	switch i.(type) {
-	case *int, *int64,
-		*string, *float32, *float64, *bool, *time.Time:
-		return true
+func getType(i interface{}, fieldNum int) string {
+	t := reflect.TypeOf(i).Elem()
+	if t.NumField() <= fieldNum {
+		return ""
 	}
 
-	return false
-}
+	f := t.Field(fieldNum)
+	if typ := f.Tag.Get("type"); typ != "" {
+		return typ
+	}
 
-func isValidKeyType(i interface{}) bool {
-	switch i.(type) {
-	case *int, *int64:
-		return true
+	ft := f.Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
 	}
 
-	return false
+	return sqlType(ft)
+}
+
+func isValidType(i interface{}) bool {
+	t := reflect.TypeOf(i)
+
+	return t.Kind() == reflect.Ptr && sqlType(t.Elem()) != ""
 }
 
 func typeName(i interface{}) string {
@@ -95,30 +174,283 @@ func typeName(i interface{}) string {
 	return name
 }
 
-func (t *typeInfo) GetID(i interface{}) int64 {
-	if !isPointerStruct(i) {
-		return 0
+func stmt(tx *sql.Tx, s *sql.Stmt) *sql.Stmt {
+	if tx == nil {
+		return s
+	}
+
+	return tx.Stmt(s)
+}
+
+func (t *typeInfo) column(name string) (field, bool) {
+	for _, f := range t.fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
 	}
 
-	switch v := getField(i, t.fields[t.primary].pos).(type) {
-	case int:
-		return int64(v)
-	case int64:
-		return v
+	return field{}, false
+}
+
+func (t *typeInfo) hasColumn(name string) bool {
+	_, ok := t.column(name)
+
+	return ok
+}
+
+type ctxMutex chan struct{}
+
+func (c ctxMutex) Lock() {
+	c <- struct{}{}
+}
+
+func (c ctxMutex) LockContext(ctx context.Context) error {
+	select {
+	case c <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	return 0
+func (c ctxMutex) Unlock() {
+	<-c
 }
 
-func (t *typeInfo) SetID(i interface{}, id int64) {
-	if !isPointerStruct(i) {
-		return
+func fieldValue(i interface{}, f field) (interface{}, error) {
+	if v := reflect.ValueOf(i).Elem().Field(f.pos); v.Kind() == reflect.Ptr && v.IsNil() || f.role == roleDeleted && v.IsZero() {
+		return nil, nil
+	} else if f.encoding == encodeNone {
+		return getField(i, f.pos), nil
 	}
 
-	switch v := getFieldPointer(i, t.fields[t.primary].pos).(type) {
-	case *int:
-		*v = int(id)
-	case *int64:
-		*v = id
+	return encodeValue(getFieldPointer(i, f.pos), f.encoding)
+}
+
+func encodeValue(p interface{}, enc uint8) (interface{}, error) {
+	switch enc {
+	case encodeValuer:
+		return p, nil
+	case encodeText:
+		text, err := p.(encoding.TextMarshaler).MarshalText()
+
+		return string(text), err
+	case encodeBytes:
+		v := reflect.ValueOf(p).Elem()
+		b := make([]byte, v.Len())
+
+		reflect.Copy(reflect.ValueOf(b), v)
+
+		return b, nil
+	case encodeUnix, encodeUnixNano, encodeRFC3339:
+		if nt, ok := p.(*sql.NullTime); ok {
+			if !nt.Valid {
+				return nil, nil
+			}
+
+			return encodeTime(nt.Time, enc), nil
+		}
+
+		return encodeTime(*p.(*time.Time), enc), nil
+	}
+
+	return reflect.ValueOf(p).Elem().Interface(), nil
+}
+
+func fieldScanner(i interface{}, f field) interface{} {
+	v := reflect.ValueOf(i).Elem().Field(f.pos)
+	if v.Kind() == reflect.Ptr {
+		return ptrScanner{v, f.encoding}
+	}
+
+	return scanner(v.Addr().Interface(), f.encoding)
+}
+
+func scanner(p interface{}, enc uint8) interface{} {
+	switch enc {
+	case encodeNone:
+		if v := reflect.ValueOf(p).Elem(); v.Kind() != reflect.Interface {
+			return nullScanner{v}
+		}
+	case encodeText:
+		return textScanner{p.(encoding.TextUnmarshaler)}
+	case encodeBytes:
+		return bytesScanner{reflect.ValueOf(p).Elem()}
+	case encodeUnix, encodeUnixNano, encodeRFC3339:
+		if nt, ok := p.(*sql.NullTime); ok {
+			return nullTimeScanner{nt, enc}
+		}
+
+		return timeScanner{p.(*time.Time), enc}
 	}
+
+	return p
+}
+
+type textScanner struct {
+	encoding.TextUnmarshaler
+}
+
+func (t textScanner) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return t.UnmarshalText([]byte(src))
+	case []byte:
+		return t.UnmarshalText(src)
+	case nil:
+		return t.UnmarshalText(nil)
+	}
+
+	return ErrInvalidType
+}
+
+type bytesScanner struct {
+	reflect.Value
+}
+
+func (b bytesScanner) Scan(src interface{}) error {
+	var data []byte
+
+	switch src := src.(type) {
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	case nil:
+		b.Set(reflect.Zero(b.Type()))
+
+		return nil
+	default:
+		return ErrInvalidType
+	}
+
+	if len(data) != b.Len() {
+		return ErrInvalidType
+	}
+
+	reflect.Copy(b.Value, reflect.ValueOf(data))
+
+	return nil
+}
+
+func fieldType(i interface{}, f field) string {
+	if f.isStruct {
+		return f.refType
+	}
+
+	typ := getType(i, f.pos)
+	if isTimeEncoding(f.encoding) && typ == "INTEGER" {
+		return timeSQLType(f.encoding)
+	}
+
+	return typ
+}
+
+// columnType returns the generic type of the column of a field, using the
+// indexable types for the text and binary columns of keys, indexes and
+// references.
+func columnType(i interface{}, f field, isKey bool) string {
+	typ := fieldType(i, f)
+	if isKey || f.isStruct || f.unique || len(f.indexes) > 0 {
+		return indexedType(typ)
+	}
+
+	return typ
+}
+
+// indexedType converts the generic TEXT and BLOB types to VARCHAR and
+// VARBINARY, as some databases can only index columns of a limited length.
+func indexedType(sqlType string) string {
+	switch sqlType {
+	case "TEXT":
+		return "VARCHAR"
+	case "BLOB":
+		return "VARBINARY"
+	}
+
+	return sqlType
+}
+
+func parseTag(f reflect.StructField) (string, []string) {
+	opts := splitOptions(f.Tag.Get("store"))
+
+	name := opts[0]
+	if name == "" {
+		name = f.Name
+	}
+
+	return name, opts[1:]
+}
+
+// splitOptions splits a tag on the commas that are not within single quotes or
+// parentheses, so that SQL values and expressions can contain commas.
+func splitOptions(tag string) []string {
+	var (
+		opts   []string
+		depth  int
+		quoted bool
+		start  int
+	)
+
+	for n, c := range tag {
+		switch c {
+		case '\'':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted && depth > 0 {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				opts = append(opts, tag[start:n])
+				start = n + 1
+			}
+		}
+	}
+
+	return append(opts, tag[start:])
+}
+
+func hasOption(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+const maxBatch = 500
+
+func placeholders(n int) string {
+	if n == 0 {
+		return ""
+	}
+
+	return "?" + strings.Repeat(", ?", n-1)
+}
+
+func (t *typeInfo) columns() string {
+	var cols string
+
+	for n := range t.keys {
+		if n > 0 {
+			cols += ", "
+		}
+
+		cols += "[" + t.keyField(n).name + "]"
+	}
+
+	for pos, f := range t.fields {
+		if !t.isKey(pos) {
+			cols += ", [" + f.name + "]"
+		}
+	}
+
+	return cols
 }
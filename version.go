@@ -0,0 +1,170 @@
+package store
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	roleNone uint8 = iota
+	roleCreated
+	roleUpdated
+	roleVersion
+	roleDeleted
+)
+
+// fieldRole determines whether a field is automatically maintained, from the
+// created, updated and version options of its store tag.
+//
+// Created and updated fields must be of type time.Time, and are set to the
+// current time when a record is inserted, and whenever it is stored,
+// respectively. Version fields must be integers, and are incremented whenever
+// a record is stored, with updates only succeeding when the version matches
+// that of the stored record. Changes to these fields are undone if the
+// transaction storing the record is rolled back. Soft delete fields must be of
+// type time.Time, or a pointer to one, and are set to the time a record is
+// removed.
+func fieldRole(opts []string, t reflect.Type) (uint8, error) {
+	var role uint8
+
+	for r, opt := range [...]string{roleCreated: "created", roleUpdated: "updated", roleVersion: "version", roleDeleted: "softdelete"} {
+		if opt == "" || !hasOption(opts, opt) {
+			continue
+		} else if role != roleNone {
+			return 0, ErrInvalidTag
+		}
+
+		role = uint8(r)
+	}
+
+	switch role {
+	case roleCreated, roleUpdated, roleDeleted:
+		if t != timeType {
+			return 0, ErrInvalidTag
+		}
+	case roleVersion:
+		if !isIntKeyType(t) {
+			return 0, ErrInvalidTag
+		}
+	}
+
+	return role, nil
+}
+
+func (t *typeInfo) hasRole(role uint8) bool {
+	for _, f := range t.fields {
+		if f.role == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// saveTracked saves the values of the created, updated and version fields of
+// a record, which are changed when it is stored.
+func (s *Store) saveTracked(i interface{}, t *typeInfo) {
+	for _, f := range t.fields {
+		if f.role == roleCreated || f.role == roleUpdated || f.role == roleVersion {
+			s.save(i, f.pos)
+		}
+	}
+}
+
+// setUpdated sets the updated fields of a record to the given time.
+func (t *typeInfo) setUpdated(i interface{}, now time.Time) {
+	for _, f := range t.fields {
+		if f.role == roleUpdated {
+			reflect.ValueOf(i).Elem().Field(f.pos).Set(reflect.ValueOf(now))
+		}
+	}
+}
+
+// setCreated sets the unset created fields of a record that is about to be
+// inserted to the given time, updating the values to be inserted.
+func (t *typeInfo) setCreated(i interface{}, vars []interface{}, now time.Time) error {
+	n := 0
+
+	for pos, f := range t.fields {
+		if t.isKey(pos) {
+			continue
+		}
+
+		if f.role == roleCreated {
+			if v := reflect.ValueOf(i).Elem().Field(f.pos); v.IsZero() {
+				v.Set(reflect.ValueOf(now))
+			}
+
+			var err error
+
+			if vars[n], err = fieldValue(i, f); err != nil {
+				return err
+			}
+		}
+
+		n++
+	}
+
+	return nil
+}
+
+// version returns the value of the version field of a record.
+func (t *typeInfo) version(i interface{}) int64 {
+	for _, f := range t.fields {
+		if f.role != roleVersion {
+			continue
+		}
+
+		v := reflect.ValueOf(i).Elem().Field(f.pos)
+		if v.CanInt() {
+			return v.Int()
+		}
+
+		return int64(v.Uint())
+	}
+
+	return 0
+}
+
+func (t *typeInfo) setVersion(i interface{}, version int64) {
+	for _, f := range t.fields {
+		if f.role != roleVersion {
+			continue
+		}
+
+		v := reflect.ValueOf(i).Elem().Field(f.pos)
+		if v.CanInt() {
+			v.SetInt(version)
+		} else {
+			v.SetUint(uint64(version))
+		}
+	}
+}
+
+// updateArgs returns the arguments to the update statement for a record,
+// which does not change created or soft delete fields and checks the previous
+// version.
+func (t *typeInfo) updateArgs(vars, key []interface{}, version int64) []interface{} {
+	args := make([]interface{}, 0, len(vars)+len(key)+1)
+	n := 0
+
+	for pos, f := range t.fields {
+		if t.isKey(pos) {
+			continue
+		}
+
+		if f.role != roleCreated && f.role != roleDeleted {
+			args = append(args, vars[n])
+		}
+
+		n++
+	}
+
+	args = append(args, key...)
+
+	if t.hasRole(roleVersion) {
+		args = append(args, version)
+	}
+
+	return args
+}
@@ -0,0 +1,195 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+type versioned struct {
+	ID       int
+	Name     string
+	Created  time.Time `store:"created,created,time=unixnano"`
+	Modified time.Time `store:"modified,updated,time=unixnano"`
+	Version  int       `store:"version,version"`
+}
+
+type versionedKey struct {
+	Slug    string    `key:"1"`
+	Created time.Time `store:"created,created"`
+	Version uint      `store:"version,version"`
+}
+
+type badRoleType struct {
+	ID      int
+	Created string `store:"created,created"`
+}
+
+type badRoleCount struct {
+	ID int
+	A  int `store:"a,version"`
+	B  int `store:"b,version"`
+}
+
+type badRolePointer struct {
+	ID      int
+	Updated *time.Time `store:"updated,updated"`
+}
+
+func TestTimestamps(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(versioned)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	before := time.Now()
+	v := versioned{Name: "A"}
+	if err = s.Set(&v); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	} else if v.Created.Before(before) || !v.Modified.Equal(v.Created) || v.Version != 1 {
+		t.Fatalf("test 1: expecting created and modified times after %s and version 1, got %s, %s, %d", before, v.Created, v.Modified, v.Version)
+	}
+	created := v.Created
+	time.Sleep(time.Millisecond)
+	v.Name = "B"
+	v.Created = time.Time{}
+	if err = s.Set(&v); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	} else if !v.Modified.After(created) || v.Version != 2 {
+		t.Fatalf("test 2: expecting modified time after %s and version 2, got %s, %d", created, v.Modified, v.Version)
+	}
+	got := versioned{ID: v.ID}
+	if err = s.Get(&got); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if !got.Created.Equal(created) {
+		t.Errorf("test 3: expecting created time %s, got %s", created, got.Created)
+	} else if !got.Modified.Equal(v.Modified) || got.Name != "B" || got.Version != 2 {
+		t.Errorf("test 3: expecting %v, got %v", v, got)
+	}
+	preset := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err = s.Insert(&versioned{ID: 10, Created: preset}); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if got := (versioned{ID: 10}); s.Get(&got) != nil || !got.Created.Equal(preset) || got.Version != 1 {
+		t.Errorf("test 4: expecting created time %s and version 1, got %s, %d", preset, got.Created, got.Version)
+	}
+}
+
+func TestOptimisticLocking(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(versioned), new(versionedKey)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	v := versioned{Name: "A"}
+	if err = s.Set(&v); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	a, b := versioned{ID: v.ID}, versioned{ID: v.ID}
+	if err = s.Get(&a, &b); err != nil {
+		t.Fatalf("test 2: received unexpected error: %s", err)
+	}
+	a.Name = "From A"
+	b.Name = "From B"
+	if err = s.Update(&a); err != nil {
+		t.Errorf("test 3: received unexpected error: %s", err)
+	} else if a.Version != 2 {
+		t.Errorf("test 3: expecting version 2, got %d", a.Version)
+	}
+	if err = s.Update(&b); err != ErrConflict {
+		t.Errorf("test 4: expecting error %s, got %v", ErrConflict, err)
+	} else if b.Version != 1 {
+		t.Errorf("test 4: expecting version to remain 1, got %d", b.Version)
+	}
+	if err = s.Set(&b); err != ErrConflict {
+		t.Errorf("test 5: expecting error %s, got %v", ErrConflict, err)
+	}
+	if err = s.Get(&b); err != nil {
+		t.Errorf("test 6: received unexpected error: %s", err)
+	} else if b.Name != "From A" {
+		t.Errorf("test 6: expecting name %q, got %q", "From A", b.Name)
+	} else if b.Name = "From B"; s.Set(&b) != nil || b.Version != 3 {
+		t.Errorf("test 6: expecting version 3, got %d", b.Version)
+	}
+	if err = s.Update(&versioned{ID: 100}); err != ErrNotFound {
+		t.Errorf("test 7: expecting error %s, got %v", ErrNotFound, err)
+	}
+	k := versionedKey{Slug: "a"}
+	if err = s.Set(&k); err != nil {
+		t.Errorf("test 8: received unexpected error: %s", err)
+	} else if k.Version != 1 || k.Created.IsZero() {
+		t.Errorf("test 8: expecting version 1 and created time, got %d, %s", k.Version, k.Created)
+	} else if err = s.Set(&k); err != nil {
+		t.Errorf("test 9: received unexpected error: %s", err)
+	} else if k.Version != 2 {
+		t.Errorf("test 9: expecting version 2, got %d", k.Version)
+	} else if err = s.Set(&versionedKey{Slug: "a", Version: 1}); err != ErrConflict {
+		t.Errorf("test 10: expecting error %s, got %v", ErrConflict, err)
+	}
+}
+
+func TestTrackedRollback(t *testing.T) {
+	s, err := newTestStore()
+	defer s.Close()
+	if err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	if err = s.Register(new(versioned)); err != nil {
+		t.Fatalf("received unexpected error: %s", err)
+	}
+	a := versioned{Name: "A"}
+	if err = s.Set(&a); err != nil {
+		t.Fatalf("test 1: received unexpected error: %s", err)
+	}
+	stored := a
+	a.Name = "B"
+	b := versioned{Name: "New"}
+	if err = s.Set(&a, &b, &struct{ ID int }{}); err != ErrUnregisteredType {
+		t.Errorf("test 2: expecting error %s, got %v", ErrUnregisteredType, err)
+	} else if a.Version != 1 || !a.Modified.Equal(stored.Modified) {
+		t.Errorf("test 2: expecting version 1 and modified time %s, got %d, %s", stored.Modified, a.Version, a.Modified)
+	} else if b != (versioned{Name: "New"}) {
+		t.Errorf("test 2: expecting record to be unchanged, got %v", b)
+	}
+	if err = s.Set(&a, &b); err != nil {
+		t.Fatalf("test 3: received unexpected error: %s", err)
+	} else if a.Version != 2 || b.Version != 1 {
+		t.Errorf("test 3: expecting versions 2 and 1, got %d and %d", a.Version, b.Version)
+	}
+	got := versioned{ID: a.ID}
+	if err = s.Get(&got); err != nil {
+		t.Errorf("test 4: received unexpected error: %s", err)
+	} else if got.Name != "B" || got.Version != 2 {
+		t.Errorf("test 4: expecting name %q and version 2, got %q and %d", "B", got.Name, got.Version)
+	}
+}
+
+func TestTrackedSQL(t *testing.T) {
+	_, queries := buildSQL(PostgreSQL, "store.versioned", new(versioned), []field{
+		{pos: 0, name: "ID"},
+		{pos: 1, name: "Name"},
+		{pos: 2, name: "created", role: roleCreated},
+		{pos: 3, name: "modified", role: roleUpdated},
+		{pos: 4, name: "version", role: roleVersion},
+	}, []int{0}, true)
+	if expected := `UPDATE "store.versioned" SET "Name" = $1, "modified" = $2, "version" = $3 WHERE "ID" = $4 AND "version" = $5;`; queries[update] != expected {
+		t.Errorf("test 1: expecting update SQL %q, got %q", expected, queries[update])
+	}
+	if queries[upsert] != "" {
+		t.Errorf("test 2: expecting no upsert SQL, got %q", queries[upsert])
+	}
+	for n, i := range []interface{}{new(badRoleType), new(badRoleCount), new(badRolePointer)} {
+		s, err := newTestStore()
+		if err != nil {
+			t.Fatalf("test %d: received unexpected error: %s", n+3, err)
+		}
+		if err = s.Register(i); err != ErrInvalidTag {
+			t.Errorf("test %d: expecting error %s, got %v", n+3, ErrInvalidTag, err)
+		}
+		s.Close()
+	}
+}